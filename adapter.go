@@ -1,6 +1,7 @@
 package mwear
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"fmt"
@@ -9,6 +10,7 @@ import (
 	"path/filepath"
 	"reflect"
 	"strings"
+	"time"
 
 	"github.com/cliqueinc/mysql-wear/sqlq"
 )
@@ -51,18 +53,33 @@ func (db *DB) MustCreateTable(structPtr interface{}) {
 
 // CreateTable creates table from struct.
 func (db *DB) CreateTable(structPtr interface{}) error {
-	createTableSQL := GenerateSchema(structPtr)
-	if debugEnabled {
-		fmt.Println(createTableSQL)
+	mod, err := parseModel(structPtr, true)
+	if err != nil {
+		return err
+	}
+	createTableSQL, err := renderTemplate(mod, createTableTemplate)
+	if err != nil {
+		return err
 	}
 	// TODO decide what to do with cmdTag aka rows created (first param)
-	_, err := db.DB.Exec(createTableSQL)
+	_, err = db.exec(context.Background(), OpCreateTable, mod.TableName, createTableSQL)
 	return err
 }
 
 // Adapter handles basic operations with mysql.
 type Adapter struct {
-	con Connection
+	con    Connection
+	ctxCon ContextConnection
+
+	// inTx is set for adapters bound to transaction by RunInTx,
+	// txDepth is the number of savepoints opened by nested RunInTx calls.
+	inTx    bool
+	txDepth int
+
+	hooks []QueryHook
+
+	// now is the source of current time for created and updated timestamps.
+	now func() time.Time
 }
 
 type Connection interface {
@@ -71,10 +88,113 @@ type Connection interface {
 	QueryRow(sql string, args ...interface{}) *sql.Row
 }
 
+// ContextConnection is a connection that supports query cancellation through context.
+// *sql.DB, *sql.Tx and *sql.Conn implement this interface.
+type ContextConnection interface {
+	ExecContext(ctx context.Context, sql string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, sql string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, sql string, args ...interface{}) *sql.Row
+}
+
 // Wrap wraps connection for dealing with select, insert, delete operaion
 // Connection can be one of *sql.DB, *sql.Tx.
+// If connection also implements ContextConnection, context passed to *Context methods
+// is propagated to mysql driver.
 func Wrap(con Connection) *Adapter {
-	return &Adapter{con}
+	a := &Adapter{con: con}
+	if ctxCon, ok := con.(ContextConnection); ok {
+		a.ctxCon = ctxCon
+	}
+	return a
+}
+
+// WrapContext wraps connection that only supports context aware methods, like *sql.Conn.
+func WrapContext(con ContextConnection) *Adapter {
+	return &Adapter{ctxCon: con}
+}
+
+// wrap wraps another connection keeping query hooks of the adapter.
+func (a *Adapter) wrap(con Connection) *Adapter {
+	wrapped := Wrap(con)
+	wrapped.hooks = a.hooks
+	wrapped.now = a.now
+	return wrapped
+}
+
+// SetClock overrides the source of current time used to fill fields tagged as created and updated,
+// so tests may use fixed time. Passing nil restores time.Now.
+func (a *Adapter) SetClock(now func() time.Time) {
+	a.now = now
+}
+
+// currentTime returns current UTC time of the adapter clock.
+func (a *Adapter) currentTime() time.Time {
+	if a.now != nil {
+		return a.now().UTC()
+	}
+	return time.Now().UTC()
+}
+
+// setInsertTimestamps fills created and updated timestamps of structs before insert.
+func (a *Adapter) setInsertTimestamps(structPtrs []interface{}) error {
+	now := a.currentTime()
+	for _, structPtr := range structPtrs {
+		mod, err := parseModel(structPtr, true)
+		if err != nil {
+			return err
+		}
+		mod.setTimestamps(reflect.ValueOf(structPtr), now, true)
+	}
+	return nil
+}
+
+// exec executes query calling query hooks, wraps an error in QueryError with op and table.
+func (a *Adapter) exec(ctx context.Context, op, table, query string, args ...interface{}) (sql.Result, error) {
+	var (
+		res sql.Result
+		err error
+	)
+	ctx, event := a.beforeQuery(ctx, op, table, query, args)
+	if a.ctxCon != nil {
+		res, err = a.ctxCon.ExecContext(ctx, query, args...)
+	} else {
+		res, err = a.con.Exec(query, args...)
+	}
+	if err != nil {
+		err = newQueryError(op, table, query, err)
+		a.afterQuery(ctx, event, 0, err)
+		return nil, err
+	}
+	if event != nil {
+		num, _ := res.RowsAffected()
+		a.afterQuery(ctx, event, num, nil)
+	}
+	return res, nil
+}
+
+// query executes select query, wrapping an error in QueryError with op and table.
+// Query hooks aren't called, so caller is expected to call them around the query and scanning of rows.
+func (a *Adapter) query(ctx context.Context, op, table, query string, args ...interface{}) (*sql.Rows, error) {
+	var (
+		rows *sql.Rows
+		err  error
+	)
+	if a.ctxCon != nil {
+		rows, err = a.ctxCon.QueryContext(ctx, query, args...)
+	} else {
+		rows, err = a.con.Query(query, args...)
+	}
+	if err != nil {
+		return nil, newQueryError(op, table, query, err)
+	}
+	return rows, nil
+}
+
+func (a *Adapter) queryRow(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	if a.ctxCon != nil {
+		return a.ctxCon.QueryRowContext(ctx, query, args...)
+	}
+	return a.con.QueryRow(query, args...)
 }
 
 // MustInsert ensures structs are inserted without errors, panics othervise.
@@ -89,12 +209,88 @@ func (a *Adapter) MustInsert(structPtrs ...interface{}) sql.Result {
 
 // Insert inserts one or more struct into db. If no options specied, struct will be updated by primary key.
 // Limit of items to insert at once is 1000 items.
+//
+// For models with auto increment primary key, generated ids are set back to inserted structs.
+// In case of multiple items ids are calculated from the first generated id, which relies on mysql
+// allocating consecutive values for one insert statement: innodb_autoinc_lock_mode should be 0 ("traditional")
+// or 1 ("consecutive"), and auto_increment_increment should be 1. Ids are set only if primary key
+// of all inserted items is empty.
+//
+// Fields tagged as created and updated are set to current UTC time.
 func (a *Adapter) Insert(structPtrs ...interface{}) (sql.Result, error) {
+	return a.InsertContext(context.Background(), structPtrs...)
+}
+
+// InsertContext is the same as Insert, but allows to cancel query using context.
+func (a *Adapter) InsertContext(ctx context.Context, structPtrs ...interface{}) (sql.Result, error) {
+	if err := beforeInsert(ctx, structPtrs); err != nil {
+		return nil, err
+	}
+	if err := a.setInsertTimestamps(structPtrs); err != nil {
+		return nil, err
+	}
+	mod, tmplData, args, err := insertData(structPtrs)
+	if err != nil {
+		return nil, err
+	}
+	insertSQL, err := renderTemplate(tmplData, insertTemplate)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := a.exec(ctx, OpInsert, mod.TableName, insertSQL, args...)
+	if err != nil {
+		return nil, err
+	}
+	if err := setInsertIDs(mod, res, structPtrs); err != nil {
+		return nil, err
+	}
+	for _, structPtr := range structPtrs {
+		mod.takeSnapshot(reflect.ValueOf(structPtr))
+	}
+	if err := afterInsert(ctx, structPtrs); err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// setInsertIDs sets auto increment ids to inserted structs in case their primary key is empty.
+func setInsertIDs(mod *model, res sql.Result, structPtrs []interface{}) error {
+	if mod.PKPos == -1 || !mod.IsIntPK() { // IsIntPK is false for composite keys
+		return nil
+	}
+	for _, structPtr := range structPtrs {
+		if !mod.Fields[mod.PKPos].value(reflect.ValueOf(structPtr), false).IsZero() {
+			return nil
+		}
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("fail get last id: %w", err)
+	}
+	for i, structPtr := range structPtrs {
+		pk := mod.Fields[mod.PKPos].value(reflect.ValueOf(structPtr), true)
+		switch pk.Kind() {
+		case reflect.Uint, reflect.Uint32, reflect.Uint64:
+			pk.SetUint(uint64(id) + uint64(i))
+		default:
+			pk.SetInt(id + int64(i))
+		}
+	}
+
+	return nil
+}
+
+// insertData parses structs that are going to be inserted, returns model,
+// data for rendering insert template and query args.
+func insertData(structPtrs []interface{}) (*model, map[string]interface{}, []interface{}, error) {
 	if len(structPtrs) == 0 {
-		return nil, errors.New("nothing to insert")
+		return nil, nil, nil, errors.New("nothing to insert")
 	}
 	if len(structPtrs) > LimitInsert {
-		return nil, fmt.Errorf("insertion of more than (%d) items not allowed", LimitInsert)
+		return nil, nil, nil, fmt.Errorf("insertion of more than (%d) items not allowed", LimitInsert)
 	}
 
 	var (
@@ -103,7 +299,10 @@ func (a *Adapter) Insert(structPtrs ...interface{}) (sql.Result, error) {
 	)
 	items := make([]interface{}, 0, len(structPtrs))
 	for i, structPtr := range structPtrs {
-		mod := parseModel(structPtr, true)
+		mod, err := parseModel(structPtr, true)
+		if err != nil {
+			return nil, nil, nil, err
+		}
 		rowModel := reflect.ValueOf(structPtr)
 		items = append(items, mod)
 		if i == 0 {
@@ -112,7 +311,7 @@ func (a *Adapter) Insert(structPtrs ...interface{}) (sql.Result, error) {
 		}
 
 		if i != 0 && mod.TableName != model.TableName {
-			return nil, errors.New("cannot insert items from different tables")
+			return nil, nil, nil, errors.New("cannot insert items from different tables")
 		}
 		args = append(args, mod.getVals(rowModel, mod.Fields)...)
 	}
@@ -120,17 +319,146 @@ func (a *Adapter) Insert(structPtrs ...interface{}) (sql.Result, error) {
 		"model": model,
 		"Items": items,
 	}
-	insertSQL := renderTemplate(tmplData, insertTemplate)
-	if debugEnabled {
-		fmt.Println(insertSQL)
+
+	return model, tmplData, args, nil
+}
+
+// Expr is a raw sql expression, that is put into query as is instead of being passed as query argument.
+// Used in UpsertMap, for example mw.Map{"visits": mw.Expr("`visits` + 1")}.
+type Expr string
+
+// UpsertResult describes the result of upsert operation.
+// Inserted and Updated are calculated from the number of affected rows: mysql reports 1 affected row
+// for each inserted row and 2 for each updated one. Rows updated with the same values are reported
+// as not affected, so for batches containing such rows counts are approximate.
+type UpsertResult struct {
+	sql.Result
+
+	Inserted int64
+	Updated  int64
+}
+
+// MustUpsert ensures structs are upserted without errors, panics othervise.
+func (a *Adapter) MustUpsert(structPtrs ...interface{}) *UpsertResult {
+	res, err := a.Upsert(structPtrs...)
+	if err != nil {
+		panic(err)
+	}
+	return res
+}
+
+// Upsert inserts one or more struct into db, in case a row with the same primary
+// or unique key already exists, all its non primary key columns are updated
+// (INSERT ... ON DUPLICATE KEY UPDATE).
+// Limit of items to upsert at once is 1000 items.
+// Created timestamp and version of existing rows are kept, unless listed in UpsertColumns.
+func (a *Adapter) Upsert(structPtrs ...interface{}) (*UpsertResult, error) {
+	return a.UpsertContext(context.Background(), structPtrs...)
+}
+
+// UpsertContext is the same as Upsert, but allows to cancel query using context.
+func (a *Adapter) UpsertContext(ctx context.Context, structPtrs ...interface{}) (*UpsertResult, error) {
+	return a.upsert(ctx, nil, nil, structPtrs)
+}
+
+// UpsertColumns is the same as Upsert, but updates only specified columns of existing rows.
+func (a *Adapter) UpsertColumns(columns []string, structPtrs ...interface{}) (*UpsertResult, error) {
+	return a.UpsertColumnsContext(context.Background(), columns, structPtrs...)
+}
+
+// UpsertColumnsContext is the same as UpsertColumns, but allows to cancel query using context.
+func (a *Adapter) UpsertColumnsContext(ctx context.Context, columns []string, structPtrs ...interface{}) (*UpsertResult, error) {
+	if len(columns) == 0 {
+		return nil, errors.New("columns for update cannot be empty")
+	}
+	return a.upsert(ctx, columns, nil, structPtrs)
+}
+
+// UpsertMap is the same as Upsert, but existing rows are updated with specified map data.
+// Values of type mw.Expr are treated as raw sql expressions, like mw.Expr("`visits` + VALUES(`visits`)").
+func (a *Adapter) UpsertMap(dataMap Map, structPtrs ...interface{}) (*UpsertResult, error) {
+	return a.UpsertMapContext(context.Background(), dataMap, structPtrs...)
+}
+
+// UpsertMapContext is the same as UpsertMap, but allows to cancel query using context.
+func (a *Adapter) UpsertMapContext(ctx context.Context, dataMap Map, structPtrs ...interface{}) (*UpsertResult, error) {
+	if len(dataMap) == 0 {
+		return nil, errors.New("columns for update cannot be empty")
+	}
+	columns := make([]string, 0, len(dataMap))
+	for col := range dataMap {
+		columns = append(columns, col)
+	}
+	return a.upsert(ctx, columns, dataMap, structPtrs)
+}
+
+// upsertValue describes one assignment in ON DUPLICATE KEY UPDATE clause.
+type upsertValue struct {
+	Column string
+	Value  string
+}
+
+func (a *Adapter) upsert(ctx context.Context, columns []string, dataMap Map, structPtrs []interface{}) (*UpsertResult, error) {
+	if err := a.setInsertTimestamps(structPtrs); err != nil {
+		return nil, err
+	}
+	mod, tmplData, args, err := insertData(structPtrs)
+	if err != nil {
+		return nil, err
+	}
+
+	fields, err := mod.GetFieldsNoPK(columns)
+	if err != nil {
+		return nil, err
+	}
+	updates := make([]upsertValue, 0, len(fields))
+	for _, f := range fields {
+		// existing rows keep creation time and version, so upsert doesn't override optimistic locking.
+		if columns == nil && (f == mod.fieldByPos(mod.CreatedPos) || f == mod.fieldByPos(mod.VersionPos)) {
+			continue
+		}
+		v := upsertValue{Column: f.MWNameQuoted(), Value: "VALUES(" + f.MWNameQuoted() + ")"}
+		if dataMap != nil {
+			val, ok := dataMap[f.MWName]
+			if !ok {
+				continue
+			}
+			if expr, ok := val.(Expr); ok {
+				v.Value = string(expr)
+			} else {
+				v.Value = "?"
+				args = append(args, val)
+			}
+		}
+		updates = append(updates, v)
+	}
+	if len(updates) == 0 { // nothing to update, keep existing row as is
+		pk := mod.GetPKField().MWNameQuoted()
+		updates = append(updates, upsertValue{Column: pk, Value: "VALUES(" + pk + ")"})
 	}
+	tmplData["updates"] = updates
 
-	res, err := a.con.Exec(insertSQL, args...)
+	upsertSQL, err := renderTemplate(tmplData, upsertTemplate)
 	if err != nil {
 		return nil, err
 	}
 
-	return res, nil
+	res, err := a.exec(ctx, OpUpsert, mod.TableName, upsertSQL, args...)
+	if err != nil {
+		return nil, err
+	}
+	num, err := res.RowsAffected()
+	if err != nil {
+		return nil, fmt.Errorf("fail get number of affected rows: %w", err)
+	}
+
+	result := &UpsertResult{Result: res}
+	if total := int64(len(structPtrs)); num > total {
+		result.Updated = num - total
+	}
+	result.Inserted = num - 2*result.Updated
+
+	return result, nil
 }
 
 // MustUpdate ensures struct will be updated without errors, panics othervise.
@@ -141,21 +469,116 @@ func (a *Adapter) MustUpdate(structPtr interface{}) {
 	}
 }
 
-// Update updates struct by primary key.
+// Update updates struct by primary key. Field tagged as updated is set to current UTC time.
+// For models with version field, the row is updated only if it has the same version, which is then incremented,
+// otherwise ErrStaleObject is returned.
+// Models embedding mw.Tracked update only columns changed since the model was loaded,
+// no query is executed if nothing changed.
 func (a *Adapter) Update(structPtr interface{}) error {
-	mod := parseModel(structPtr, true)
-	fieldsNoPK := mod.GetFieldsNoPK(nil)
+	return a.UpdateContext(context.Background(), structPtr)
+}
 
+// UpdateContext is the same as Update, but allows to cancel query using context.
+func (a *Adapter) UpdateContext(ctx context.Context, structPtr interface{}) error {
+	return a.update(ctx, structPtr, nil)
+}
+
+// UpdateColumns is the same as Update, but updates only specified columns.
+// Columns tagged as updated and version are updated as well.
+func (a *Adapter) UpdateColumns(structPtr interface{}, columns ...string) error {
+	return a.UpdateColumnsContext(context.Background(), structPtr, columns...)
+}
+
+// UpdateColumnsContext is the same as UpdateColumns, but allows to cancel query using context.
+func (a *Adapter) UpdateColumnsContext(ctx context.Context, structPtr interface{}, columns ...string) error {
+	if len(columns) == 0 {
+		return errors.New("columns for update cannot be empty")
+	}
+	return a.update(ctx, structPtr, columns)
+}
+
+func (a *Adapter) update(ctx context.Context, structPtr interface{}, columns []string) error {
+	mod, err := parseModel(structPtr, true)
+	if err != nil {
+		return err
+	}
 	rowModel := reflect.ValueOf(structPtr)
-	args := append(mod.getVals(rowModel, fieldsNoPK), mod.getPK(rowModel))
-	updateSQL := renderTemplate(Map{"mod": mod, "fields": fieldsNoPK}, fmt.Sprintf("%s WHERE `{{.mod.PKName}}` = ?;", updateTemplate))
-	if debugEnabled {
-		fmt.Println(updateSQL)
+	if h, ok := structPtr.(BeforeUpdater); ok {
+		if err := h.BeforeUpdate(ctx); err != nil {
+			return err
+		}
+	}
+	// updated time is restored in case of an error, so the struct keeps matching the row.
+	restoreUpdated := func() {}
+	if mod.UpdatedPos != -1 {
+		updatedField := mod.Fields[mod.UpdatedPos].value(rowModel, true)
+		updated := updatedField.Interface()
+		restoreUpdated = func() { updatedField.Set(reflect.ValueOf(updated)) }
 	}
-	_, err := a.con.Exec(updateSQL, args...)
+	mod.setTimestamps(rowModel, a.currentTime(), false)
+	fieldsNoPK, err := mod.updateFields(rowModel, columns)
 	if err != nil {
+		restoreUpdated()
 		return err
 	}
+	if len(fieldsNoPK) == 0 {
+		// nothing changed, so update time is kept as loaded.
+		if mod.UpdatedPos != -1 {
+			mod.restoreField(rowModel, mod.UpdatedPos)
+		}
+		return nil
+	}
+
+	if mod.VersionPos != -1 {
+		err = a.updateVersion(ctx, mod, rowModel, fieldsNoPK)
+	} else {
+		args := append(mod.getVals(rowModel, fieldsNoPK), mod.pkVals(rowModel)...)
+		var updateSQL string
+		updateSQL, err = renderTemplate(Map{"mod": mod, "fields": fieldsNoPK}, fmt.Sprintf("%s WHERE %s;", updateTemplate, mod.pkCondition(false)))
+		if err == nil {
+			_, err = a.exec(ctx, OpUpdate, mod.TableName, updateSQL, args...)
+		}
+	}
+	if err != nil {
+		restoreUpdated()
+		return err
+	}
+	mod.takeSnapshot(rowModel)
+	if h, ok := structPtr.(AfterUpdater); ok {
+		return h.AfterUpdate(ctx)
+	}
+
+	return nil
+}
+
+// updateVersion updates model with version field by primary key and current version, incrementing the version.
+// ErrStaleObject is returned if the row has another version or doesn't exist.
+func (a *Adapter) updateVersion(ctx context.Context, mod *model, rowModel reflect.Value, fields []*field) error {
+	versionField := mod.Fields[mod.VersionPos].value(rowModel, true)
+	version := versionField.Int()
+	versionField.SetInt(version + 1)
+
+	args := append(append(mod.getVals(rowModel, fields), mod.pkVals(rowModel)...), version)
+	versionCol := mod.fieldByPos(mod.VersionPos).MWNameQuoted()
+	updateSQL, err := renderTemplate(Map{"mod": mod, "fields": fields}, fmt.Sprintf("%s WHERE %s AND %s = ?;", updateTemplate, mod.pkCondition(false), versionCol))
+	if err != nil {
+		versionField.SetInt(version)
+		return err
+	}
+	res, err := a.exec(ctx, OpUpdate, mod.TableName, updateSQL, args...)
+	if err != nil {
+		versionField.SetInt(version)
+		return err
+	}
+	num, err := res.RowsAffected()
+	if err != nil {
+		versionField.SetInt(version)
+		return fmt.Errorf("fail get number of affected rows: %w", err)
+	}
+	if num == 0 {
+		versionField.SetInt(version)
+		return fmt.Errorf("%w: table (%s) primary key (%s) version (%d)", ErrStaleObject, mod.TableName, mod.getPK(rowModel), version)
+	}
 
 	return nil
 }
@@ -175,7 +598,13 @@ func (a *Adapter) MustUpdateRows(structPtr interface{}, dataMap Map, opts ...sql
 // UpdateRows updates rows with specified map data by query, returns number of affected rows.
 // In case when you really need to update all rows (e.g. migration script), you need to pass mw.QueryAll() option.
 // It is done to avoid unintentional update of all rows.
+// Column of the field tagged as updated is set to current UTC time, unless present in map data.
 func (a *Adapter) UpdateRows(structPtr interface{}, dataMap Map, opts ...sqlq.Option) (int64, error) {
+	return a.UpdateRowsContext(context.Background(), structPtr, dataMap, opts...)
+}
+
+// UpdateRowsContext is the same as UpdateRows, but allows to cancel query using context.
+func (a *Adapter) UpdateRowsContext(ctx context.Context, structPtr interface{}, dataMap Map, opts ...sqlq.Option) (int64, error) {
 	if len(dataMap) == 0 {
 		return 0, errors.New("columns for update cannot be empty")
 	}
@@ -183,13 +612,30 @@ func (a *Adapter) UpdateRows(structPtr interface{}, dataMap Map, opts ...sqlq.Op
 		return 0, errors.New("query options cannot be empty")
 	}
 
+	mod, err := parseModel(structPtr, true)
+	if err != nil {
+		return 0, err
+	}
+	if mod.UpdatedPos != -1 {
+		updatedCol := mod.fieldByPos(mod.UpdatedPos).MWName
+		if _, ok := dataMap[updatedCol]; !ok {
+			data := make(Map, len(dataMap)+1)
+			for col, val := range dataMap {
+				data[col] = val
+			}
+			data[updatedCol] = a.currentTime()
+			dataMap = data
+		}
+	}
+
 	columns := make([]string, 0, len(dataMap))
 	for col := range dataMap {
 		columns = append(columns, col)
 	}
-
-	mod := parseModel(structPtr, true)
-	fieldsNoPK := mod.GetFieldsNoPK(columns)
+	fieldsNoPK, err := mod.GetFieldsNoPK(columns)
+	if err != nil {
+		return 0, err
+	}
 	args := make([]interface{}, 0, len(dataMap))
 	for _, f := range fieldsNoPK {
 		val, ok := dataMap[f.MWName]
@@ -209,18 +655,18 @@ func (a *Adapter) UpdateRows(structPtr interface{}, dataMap Map, opts ...sqlq.Op
 	}
 
 	updateTpl := updateTemplate + " " + stmt.Query + ";"
-	updateSQL := renderTemplate(Map{"mod": mod, "fields": fieldsNoPK}, updateTpl)
-	if debugEnabled {
-		fmt.Println(updateSQL)
+	updateSQL, err := renderTemplate(Map{"mod": mod, "fields": fieldsNoPK}, updateTpl)
+	if err != nil {
+		return 0, err
 	}
 
-	res, err := a.con.Exec(updateSQL, stmt.Args...)
+	res, err := a.exec(ctx, OpUpdate, mod.TableName, updateSQL, stmt.Args...)
 	if err != nil {
-		return 0, fmt.Errorf("update error: %v", err)
+		return 0, err
 	}
 	num, err := res.RowsAffected()
 	if err != nil {
-		return 0, fmt.Errorf("fail get number of affected rows: %v", err)
+		return 0, fmt.Errorf("fail get number of affected rows: %w", err)
 	}
 
 	return num, nil
@@ -236,28 +682,165 @@ func (a *Adapter) MustSelect(structPtr interface{}, opts ...sqlq.Option) {
 
 // Select performs select using query options. If no options specified, all rows will be returned.
 // destSlicePtr parameter expects pointer to a slice
+// Soft deleted rows are skipped, unless sqlq.WithDeleted or sqlq.OnlyDeleted option passed.
 func (a *Adapter) Select(destSlicePtr interface{}, opts ...sqlq.Option) error {
-	stmt, err := sqlq.Build(opts, sqlq.OpSelect)
+	return a.SelectContext(context.Background(), destSlicePtr, opts...)
+}
+
+// SelectContext is the same as Select, but allows to cancel query using context.
+func (a *Adapter) SelectContext(ctx context.Context, destSlicePtr interface{}, opts ...sqlq.Option) error {
+	mod, _, _, err := parseDestSlice(destSlicePtr)
+	if err != nil {
+		return err
+	}
+	stmt, err := sqlq.Build(mod.withSoftDelete(opts), sqlq.OpSelect)
 	if err != nil {
 		return err
 	}
 
+	return a.selectStmt(ctx, destSlicePtr, stmt)
+}
+
+func (a *Adapter) selectStmt(ctx context.Context, destSlicePtr interface{}, stmt *sqlq.Query) error {
 	mod, sliceValElement, sliceTypeElement, err := parseDestSlice(destSlicePtr)
 	if err != nil {
 		return err
 	}
-	fields := mod.getFields(stmt.Columns)
-	joinMods, joinFields, err := processJoins(mod, stmt.Joins)
+	finalSQL, joinMods, joinFields, err := selectSQL(mod, stmt)
 	if err != nil {
 		return err
 	}
 
-	finalSQL := renderTemplate(Map{"mod": mod, "fields": fields, "joins": stmt.Joins, "joinFields": joinFields, "joinMods": joinMods}, selectBaseTemplate) + " " + stmt.Query + ";"
-	if debugEnabled {
-		fmt.Println(finalSQL)
+	return a.rawSelect(ctx, OpSelect, mod.TableName, finalSQL, stmt.Columns, joinMods, joinFields, true, sliceValElement, sliceTypeElement, stmt.Args...)
+}
+
+// selectSQL renders select query of the model with joins, returns query along with joined models and their fields.
+func selectSQL(mod *model, stmt *sqlq.Query) (string, []*model, [][]*field, error) {
+	fields, err := mod.getFields(stmt.Columns)
+	if err != nil {
+		return "", nil, nil, err
+	}
+	joinMods, joinFields, err := processJoins(mod, stmt.Joins)
+	if err != nil {
+		return "", nil, nil, err
+	}
+
+	finalSQL, err := renderTemplate(Map{"mod": mod, "fields": fields, "joins": stmt.Joins, "joinFields": joinFields, "joinMods": joinMods}, selectBaseTemplate)
+	if err != nil {
+		return "", nil, nil, err
 	}
 
-	return a.rawSelect(finalSQL, stmt.Columns, joinMods, joinFields, true, sliceValElement, sliceTypeElement, stmt.Args...)
+	return finalSQL + " " + stmt.Query + ";", joinMods, joinFields, nil
+}
+
+// MustSelectPage ensures page select will not produce any error, panics othervise.
+func (a *Adapter) MustSelectPage(destSlicePtr interface{}, opts ...sqlq.Option) *sqlq.Page {
+	page, err := a.SelectPage(destSlicePtr, opts...)
+	if err != nil {
+		panic(err)
+	}
+	return page
+}
+
+// SelectPage performs select using keyset (cursor) pagination. Page is selected after or before
+// the cursor passed with sqlq.After or sqlq.Before option, in order specified by sqlq.Order options,
+// primary key is added to order to make it stable. Page size is set by sqlq.Limit.
+// Like Select, rows are appended to the slice. Returns cursors of the next and previous pages.
+func (a *Adapter) SelectPage(destSlicePtr interface{}, opts ...sqlq.Option) (*sqlq.Page, error) {
+	return a.SelectPageContext(context.Background(), destSlicePtr, opts...)
+}
+
+// SelectPageContext is the same as SelectPage, but allows to cancel query using context.
+func (a *Adapter) SelectPageContext(ctx context.Context, destSlicePtr interface{}, opts ...sqlq.Option) (*sqlq.Page, error) {
+	mod, sliceValElement, _, err := parseDestSlice(destSlicePtr)
+	if err != nil {
+		return nil, err
+	}
+	if mod.PKName == "" {
+		return nil, fmt.Errorf("missing primary key for table (%s)", mod.TableName)
+	}
+
+	pkColumns := make([]string, 0, len(mod.PKFields))
+	for _, f := range mod.PKFields {
+		pkColumns = append(pkColumns, "`"+mod.TableName+"`."+f.MWNameQuoted())
+	}
+	pageOpts := make([]sqlq.Option, 0, len(opts)+1)
+	pageOpts = append(append(pageOpts, opts...), sqlq.UniqueOrder(pkColumns...))
+	stmt, err := sqlq.Build(mod.withSoftDelete(pageOpts), sqlq.OpSelect)
+	if err != nil {
+		return nil, err
+	}
+	// rows are appended to the slice, so elements it already has are not a part of the page.
+	loadedFrom := sliceValElement.Len()
+	if err := a.selectStmt(ctx, destSlicePtr, stmt); err != nil {
+		return nil, err
+	}
+
+	ks := stmt.Keyset
+	hasMore := ks.Limit != 0 && sliceValElement.Len()-loadedFrom > ks.Limit
+	if hasMore {
+		sliceValElement.SetLen(loadedFrom + ks.Limit)
+	}
+	rows := sliceValElement.Slice(loadedFrom, sliceValElement.Len())
+	rowsNum := rows.Len()
+	if ks.Before {
+		// previous page is selected in reversed order.
+		for i := 0; i < rowsNum/2; i++ {
+			first, last := rows.Index(i), rows.Index(rowsNum-i-1)
+			tmp := reflect.New(first.Type()).Elem()
+			tmp.Set(first)
+			first.Set(last)
+			last.Set(tmp)
+		}
+	}
+
+	page := &sqlq.Page{}
+	if rowsNum == 0 {
+		return page, nil
+	}
+	var firstCursor, lastCursor string
+	if firstCursor, err = mod.cursor(rows.Index(0), ks.Columns); err != nil {
+		return nil, err
+	}
+	if lastCursor, err = mod.cursor(rows.Index(rowsNum-1), ks.Columns); err != nil {
+		return nil, err
+	}
+
+	if ks.Before {
+		page.Next = lastCursor
+		if hasMore {
+			page.Prev = firstCursor
+		}
+	} else {
+		if hasMore {
+			page.Next = lastCursor
+		}
+		if ks.Cursor != "" {
+			page.Prev = firstCursor
+		}
+	}
+
+	return page, nil
+}
+
+// cursor encodes values of specified columns of a row to page cursor.
+func (mod *model) cursor(row reflect.Value, columns []string) (string, error) {
+	values := make([]interface{}, 0, len(columns))
+	for _, col := range columns {
+		var colField *field
+		for _, f := range mod.Fields {
+			if f.MWName == col {
+				colField = f
+				break
+			}
+		}
+		if colField == nil {
+			return "", fmt.Errorf("cannot make page cursor: unrecognized order column (%s)", col)
+		}
+		values = append(values, colField.value(row, false).Interface())
+	}
+
+	return sqlq.EncodeCursor(values...)
 }
 
 func parseDestSlice(destSlicePtr interface{}) (*model, reflect.Value, reflect.Type, error) {
@@ -267,7 +850,7 @@ func parseDestSlice(destSlicePtr interface{}) (*model, reflect.Value, reflect.Ty
 	)
 
 	rt := reflect.TypeOf(destSlicePtr)
-	if rt.Kind() != reflect.Ptr {
+	if rt == nil || rt.Kind() != reflect.Ptr || rt.Elem().Kind() != reflect.Slice {
 		return nil, defaultVal, defaultType, errors.New("please pass a pointer to slice of structs for SelectAllWhere.destSlicePtr")
 	}
 	rv := reflect.ValueOf(destSlicePtr)
@@ -277,15 +860,17 @@ func parseDestSlice(destSlicePtr interface{}) (*model, reflect.Value, reflect.Ty
 	sliceValElement := rv.Elem()
 	sliceTypeElement := rt.Elem().Elem()
 
-	if rt.Kind() != reflect.Ptr || sliceValElement.Kind() != reflect.Slice ||
-		sliceTypeElement.Kind() != reflect.Struct {
+	if sliceValElement.Kind() != reflect.Slice || sliceTypeElement.Kind() != reflect.Struct {
 		return nil, defaultVal, defaultType, errors.New("please pass a pointer to slice of structs for SelectAllWhere.destSlicePtr")
 	}
 
 	// Create a new instance of the slice type for model parsing to render
 	// the template to create the sql!
 	newThang := reflect.New(sliceTypeElement)
-	mod := parseModel(newThang.Interface(), false)
+	mod, err := parseModel(newThang.Interface(), false)
+	if err != nil {
+		return nil, defaultVal, defaultType, err
+	}
 
 	return mod, sliceValElement, sliceTypeElement, nil
 }
@@ -299,42 +884,59 @@ func (a *Adapter) MustGet(structPtr interface{}, opts ...sqlq.Option) bool {
 	return found
 }
 
-// Get gets struct by primary key or by specified options.
+// Get gets struct by primary key or by specified options. In case options have no conditions
+// (like sqlq.Columns or sqlq.WithDeleted), struct is fetched by primary key.
+// Soft deleted rows aren't found, unless sqlq.WithDeleted or sqlq.OnlyDeleted option passed.
 func (a *Adapter) Get(structPtr interface{}, opts ...sqlq.Option) (found bool, err error) {
+	return a.GetContext(context.Background(), structPtr, opts...)
+}
+
+// GetContext is the same as Get, but allows to cancel query using context.
+func (a *Adapter) GetContext(ctx context.Context, structPtr interface{}, opts ...sqlq.Option) (found bool, err error) {
 	getTpl := selectBaseTemplate
 	var (
-		query   = "WHERE `{{.mod.PKName}}` = ?"
+		query   string
 		args    []interface{}
 		columns []string
 		stmt    sqlq.Query
 	)
-	if len(opts) != 0 {
+	mod, err := parseModel(structPtr, true)
+	if err != nil {
+		return false, err
+	}
+	rowModel := reflect.ValueOf(structPtr)
+	if len(opts) != 0 || mod.SoftDeletePos != -1 {
 		s, err := sqlq.Build(opts, sqlq.OpSelect)
 		if err != nil {
 			return false, err
 		}
+		if !strings.Contains(s.Query, "WHERE") {
+			// options without conditions, like sqlq.WithDeleted, are applied to the row with the same primary key.
+			pkOpt := sqlq.Raw(mod.pkCondition(true), mod.pkVals(rowModel)...)
+			opts = append([]sqlq.Option{pkOpt}, opts...)
+		}
+		if s, err = sqlq.Build(mod.withSoftDelete(opts), sqlq.OpSelect); err != nil {
+			return false, err
+		}
 		stmt = *s
 		query = stmt.Query
 		args = stmt.Args
 		columns = stmt.Columns
+	} else {
+		query = "WHERE " + mod.pkCondition(false)
+		args = mod.pkVals(rowModel)
 	}
-	mod := parseModel(structPtr, true)
-	fields := mod.getFields(columns)
-	rowModel := reflect.ValueOf(structPtr)
-	if len(opts) == 0 {
-		args = []interface{}{mod.getPK(rowModel)}
+	fields, err := mod.getFields(columns)
+	if err != nil {
+		return false, err
 	}
 	if stmt.Joins != nil {
-		joinMods, joinFields, err := processJoins(mod, stmt.Joins)
+		finalSQL, joinMods, joinFields, err := selectSQL(mod, &stmt)
 		if err != nil {
 			return false, err
 		}
-		finalSQL := renderTemplate(Map{"mod": mod, "fields": fields, "joins": stmt.Joins, "joinFields": joinFields, "joinMods": joinMods}, selectBaseTemplate) + " " + stmt.Query + ";"
-		if debugEnabled {
-			fmt.Println(finalSQL)
-		}
 		sliceValElement := reflect.New(reflect.SliceOf(mod.ReflectType.Elem()))
-		if err := a.rawSelect(finalSQL, stmt.Columns, joinMods, joinFields, true, sliceValElement.Elem(), mod.ReflectType.Elem(), stmt.Args...); err != nil {
+		if err := a.rawSelect(ctx, OpGet, mod.TableName, finalSQL, stmt.Columns, joinMods, joinFields, true, sliceValElement.Elem(), mod.ReflectType.Elem(), stmt.Args...); err != nil {
 			return false, err
 		}
 		if sliceValElement.Elem().Len() == 0 {
@@ -346,20 +948,22 @@ func (a *Adapter) Get(structPtr interface{}, opts ...sqlq.Option) (found bool, e
 	}
 
 	getTpl += " " + query + ";"
-	getSQL := renderTemplate(Map{"mod": mod, "fields": fields}, getTpl)
-	if debugEnabled {
-		fmt.Println(getSQL, args)
+	getSQL, err := renderTemplate(Map{"mod": mod, "fields": fields}, getTpl)
+	if err != nil {
+		return false, err
 	}
 
-	row := a.con.QueryRow(getSQL, args...)
+	ctx, event := a.beforeQuery(ctx, OpGet, mod.TableName, getSQL, args)
+	row := a.queryRow(ctx, getSQL, args...)
 
 	valAddrs := make([]interface{}, 0, len(fields))
 	for i := range fields {
-		val := rowModel.Elem().Field(fields[i].FieldPos).Addr().Interface()
-		if fields[i].MWType == mw_json {
+		fv := fields[i].value(rowModel, true)
+		val := fv.Addr().Interface()
+		if fields[i].JSON && !fields[i].Ptr {
 			val = &jsonScanner{val}
 		} else if fields[i].Nullable {
-			val = &nullScanner{rowModel.Elem().Field(fields[i].FieldPos), fields[i]}
+			val = &nullScanner{fv, fields[i]}
 		}
 		valAddrs = append(valAddrs, val)
 	}
@@ -367,9 +971,17 @@ func (a *Adapter) Get(structPtr interface{}, opts ...sqlq.Option) (found bool, e
 	err = row.Scan(valAddrs...)
 	if err != nil {
 		if err == sql.ErrNoRows {
+			a.afterQuery(ctx, event, 0, nil)
 			return false, nil
 		}
-		return false, fmt.Errorf("scan error: %v", err)
+		err = newQueryError(OpGet, mod.TableName, getSQL, err)
+		a.afterQuery(ctx, event, 0, err)
+		return false, err
+	}
+	a.afterQuery(ctx, event, 1, nil)
+	mod.takeSnapshot(rowModel)
+	if err := afterLoad(ctx, structPtr); err != nil {
+		return false, err
 	}
 
 	return true, nil
@@ -383,21 +995,46 @@ func (a *Adapter) MustDelete(structPtr interface{}) {
 }
 
 // Delete deletes struct by primary key or by specified options.
+// Models with soft delete field are marked as deleted, HardDelete removes them.
 func (a *Adapter) Delete(structPtr interface{}) error {
-	mod := parseModel(structPtr, true)
+	return a.DeleteContext(context.Background(), structPtr)
+}
+
+// DeleteContext is the same as Delete, but allows to cancel query using context.
+func (a *Adapter) DeleteContext(ctx context.Context, structPtr interface{}) error {
+	return a.delete(ctx, structPtr, false)
+}
+
+// delete deletes struct by primary key, models with soft delete field are only marked as deleted unless hard is set.
+func (a *Adapter) delete(ctx context.Context, structPtr interface{}, hard bool) error {
+	mod, err := parseModel(structPtr, true)
+	if err != nil {
+		return err
+	}
 	rowModel := reflect.ValueOf(structPtr)
-	pkVal := mod.getPK(rowModel)
-	if pkVal == "" {
+	if mod.getPK(rowModel) == "" {
 		return fmt.Errorf("mw cant delete from table (%s), ID/PK not set", mod.TableName)
 	}
-	deleteSQL := renderTemplate(mod, deleteTemplate+" WHERE `{{.PKName}}` = ?")
-	if debugEnabled {
-		fmt.Println(deleteSQL)
+	if h, ok := structPtr.(BeforeDeleter); ok {
+		if err := h.BeforeDelete(ctx); err != nil {
+			return err
+		}
+	}
+	if !hard && mod.SoftDeletePos != -1 {
+		err = a.softDelete(ctx, mod, rowModel)
+	} else {
+		var deleteSQL string
+		deleteSQL, err = renderTemplate(mod, deleteTemplate+" WHERE "+mod.pkCondition(false))
+		if err != nil {
+			return err
+		}
+		_, err = a.exec(ctx, OpDelete, mod.TableName, deleteSQL, mod.pkVals(rowModel)...)
 	}
-
-	_, err := a.con.Exec(deleteSQL, pkVal)
 	if err != nil {
-		return fmt.Errorf("delete error: %v", err)
+		return err
+	}
+	if h, ok := structPtr.(AfterDeleter); ok {
+		return h.AfterDelete(ctx)
 	}
 
 	return nil
@@ -418,8 +1055,25 @@ func (a *Adapter) MustDeleteRows(structPtr interface{}, opts ...sqlq.Option) int
 // DeleteRows deletes rows by specified options. Returns number of affected rows.
 // In case when you really need to update all rows (e.g. migration script), you need to pass mw.QueryAll() option.
 // It is done to avoid unintentional update of all rows.
+// Rows of models with soft delete field are marked as deleted, HardDeleteRows removes them.
 func (a *Adapter) DeleteRows(structPtr interface{}, opts ...sqlq.Option) (int64, error) {
-	mod := parseModel(structPtr, true)
+	return a.DeleteRowsContext(context.Background(), structPtr, opts...)
+}
+
+// DeleteRowsContext is the same as DeleteRows, but allows to cancel query using context.
+func (a *Adapter) DeleteRowsContext(ctx context.Context, structPtr interface{}, opts ...sqlq.Option) (int64, error) {
+	return a.deleteRows(ctx, structPtr, opts, false)
+}
+
+// deleteRows deletes rows by query, rows of models with soft delete field are only marked as deleted unless hard is set.
+func (a *Adapter) deleteRows(ctx context.Context, structPtr interface{}, opts []sqlq.Option, hard bool) (int64, error) {
+	mod, err := parseModel(structPtr, true)
+	if err != nil {
+		return 0, err
+	}
+	if !hard && mod.SoftDeletePos != -1 {
+		return a.softDeleteRows(ctx, mod, opts)
+	}
 	stmt, err := sqlq.Build(opts, sqlq.OpDelete)
 	if err != nil {
 		return 0, err
@@ -429,18 +1083,18 @@ func (a *Adapter) DeleteRows(structPtr interface{}, opts ...sqlq.Option) (int64,
 	}
 
 	deleteTpl := deleteTemplate + " " + stmt.Query + ";"
-	deleteSQL := renderTemplate(mod, deleteTpl)
-	if debugEnabled {
-		fmt.Println(deleteSQL)
+	deleteSQL, err := renderTemplate(mod, deleteTpl)
+	if err != nil {
+		return 0, err
 	}
 
-	res, err := a.con.Exec(deleteSQL, stmt.Args...)
+	res, err := a.exec(ctx, OpDelete, mod.TableName, deleteSQL, stmt.Args...)
 	if err != nil {
-		return 0, fmt.Errorf("delete error: %v", err)
+		return 0, err
 	}
 	num, err := res.RowsAffected()
 	if err != nil {
-		return 0, fmt.Errorf("fail get number of affected rows: %v", err)
+		return 0, fmt.Errorf("fail get number of affected rows: %w", err)
 	}
 
 	return num, nil
@@ -456,40 +1110,55 @@ func (a *Adapter) MustCount(model interface{}, opts ...sqlq.Option) int {
 	return count
 }
 
-// Count gets rows count by query.
+// Count gets rows count by query. Soft deleted rows aren't counted, unless sqlq.WithDeleted option passed.
 func (a *Adapter) Count(model interface{}, opts ...sqlq.Option) (int, error) {
+	return a.CountContext(context.Background(), model, opts...)
+}
+
+// CountContext is the same as Count, but allows to cancel query using context.
+func (a *Adapter) CountContext(ctx context.Context, model interface{}, opts ...sqlq.Option) (int, error) {
 	type rowsCount struct {
 		Count int `sql_name:"COUNT(*) as count"`
 	}
 
 	// use table of a given model
-	originModel := parseModel(model, true)
+	originModel, err := parseModel(model, true)
+	if err != nil {
+		return 0, err
+	}
 
 	var rows []rowsCount
 	mod, sliceValElement, sliceTypeElement, err := parseDestSlice(&rows)
 	if err != nil {
 		return 0, err
 	}
-	stmt, err := sqlq.Build(opts, sqlq.OpSelect)
+	stmt, err := sqlq.Build(originModel.withSoftDelete(opts), sqlq.OpSelect)
 	if err != nil {
 		return 0, err
 	}
-	mod.TableName = originModel.TableName
-	customFields := make([]*field, 0, len(mod.Fields))
-	for _, f := range mod.getFields(stmt.Columns) {
+	fields, err := mod.getFields(stmt.Columns)
+	if err != nil {
+		return 0, err
+	}
+	// copy cached model, so the table name isn't shared between counts of different tables.
+	countMod := *mod
+	countMod.TableName = originModel.TableName
+	mod = &countMod
+	customFields := make([]*field, 0, len(fields))
+	for _, f := range fields {
 		field := *f
 		field.TableName = mod.TableName
 		customFields = append(customFields, &field)
 	}
 
-	finalSQL := renderTemplate(Map{"mod": mod, "fields": customFields}, selectBaseTemplate)
+	finalSQL, err := renderTemplate(Map{"mod": mod, "fields": customFields}, selectBaseTemplate)
+	if err != nil {
+		return 0, err
+	}
 
 	finalSQL += " " + stmt.Query + ";"
-	if debugEnabled {
-		fmt.Println(finalSQL)
-	}
 
-	if err := a.rawSelect(finalSQL, stmt.Columns, nil, nil, false, sliceValElement, sliceTypeElement, stmt.Args...); err != nil {
+	if err := a.rawSelect(ctx, OpCount, mod.TableName, finalSQL, stmt.Columns, nil, nil, false, sliceValElement, sliceTypeElement, stmt.Args...); err != nil {
 		return 0, err
 	}
 	if len(rows) == 0 {
@@ -507,7 +1176,10 @@ func processJoins(mod *model, joinConfigs []sqlq.JoinConfig) ([]*model, [][]*fie
 	joins := make([]*model, 0, len(joinConfigs))
 	joinFields := make([][]*field, 0, len(joinConfigs))
 	for i := range joinConfigs {
-		joinMod := parseModel(joinConfigs[i].StructPtr, true)
+		joinMod, err := parseModel(joinConfigs[i].StructPtr, true)
+		if err != nil {
+			return nil, nil, err
+		}
 		if _, ok := mod.Joins[joinMod.ReflectType.Elem().Name()]; !ok && !joinMod.NoFields {
 			return nil, nil, fmt.Errorf("unknown join relation %s, fields to be joined should be marked with tag mw:\"join\"", joinMod.ReflectType.String())
 		}
@@ -515,8 +1187,12 @@ func processJoins(mod *model, joinConfigs []sqlq.JoinConfig) ([]*model, [][]*fie
 		if joinMod.NoFields {
 			continue
 		}
+		fields, err := joinMod.getFields(joinConfigs[i].Columns)
+		if err != nil {
+			return nil, nil, err
+		}
 		joins = append(joins, joinMod)
-		joinFields = append(joinFields, joinMod.getFields(joinConfigs[i].Columns))
+		joinFields = append(joinFields, fields)
 	}
 
 	return joins, joinFields, nil
@@ -1,8 +1,14 @@
 package mwear
 
 import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
 	"testing"
 	"time"
+
+	"github.com/go-sql-driver/mysql"
 )
 
 func TestAdapter(t *testing.T) {
@@ -164,3 +170,168 @@ func TestAdapter(t *testing.T) {
 		}
 	})
 }
+
+func TestAdapterContext(t *testing.T) {
+	type adapterCtxUser struct {
+		ID   string
+		Name string
+	}
+	db.MustCreateTable(&adapterCtxUser{})
+
+	u := &adapterCtxUser{ID: RandomString(30), Name: RandomString(10)}
+	if _, err := db.InsertContext(context.Background(), u); err != nil {
+		t.Fatalf("failed to insert row: %s", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	var users []adapterCtxUser
+	if err := db.SelectContext(ctx, &users); err == nil {
+		t.Error("select with canceled context expected to fail")
+	}
+	if _, err := db.GetContext(ctx, &adapterCtxUser{ID: u.ID}); err == nil {
+		t.Error("get with canceled context expected to fail")
+	}
+	if err := db.UpdateContext(ctx, u); err == nil {
+		t.Error("update with canceled context expected to fail")
+	}
+
+	conn, err := db.DB.Conn(context.Background())
+	if err != nil {
+		t.Fatalf("cannot get connection: %s", err)
+	}
+	defer conn.Close()
+
+	u2 := &adapterCtxUser{ID: u.ID}
+	found, err := WrapContext(conn).GetContext(context.Background(), u2)
+	if err != nil {
+		t.Fatalf("failed get user (%s): %s", u.ID, err)
+	}
+	if !found || u2.Name != u.Name {
+		t.Errorf("user (%s) expected to be found using wrapped *sql.Conn", u.ID)
+	}
+}
+
+func TestRunInTx(t *testing.T) {
+	type txUser struct {
+		ID   string
+		Name string
+	}
+	db.MustCreateTable(&txUser{})
+
+	t.Run("commit", func(t *testing.T) {
+		u := &txUser{ID: RandomString(30), Name: RandomString(10)}
+		err := db.RunInTx(context.Background(), nil, func(a *Adapter) error {
+			_, err := a.Insert(u)
+			return err
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if found := db.MustGet(&txUser{ID: u.ID}); !found {
+			t.Error("transaction changes should have been preserved")
+		}
+	})
+	t.Run("rollback on error", func(t *testing.T) {
+		u := &txUser{ID: RandomString(30), Name: RandomString(10)}
+		fnErr := errors.New("some error")
+		err := db.RunInTx(context.Background(), nil, func(a *Adapter) error {
+			if _, err := a.Insert(u); err != nil {
+				return err
+			}
+			return fnErr
+		})
+		if err != fnErr {
+			t.Fatalf("expected fn error, actual: %v", err)
+		}
+		if found := db.MustGet(&txUser{ID: u.ID}); found {
+			t.Error("transaction changes should have been discarded")
+		}
+	})
+	t.Run("rollback on panic", func(t *testing.T) {
+		u := &txUser{ID: RandomString(30), Name: RandomString(10)}
+		func() {
+			defer func() {
+				if r := recover(); r == nil {
+					t.Errorf("panic expected to be propagated")
+				}
+			}()
+			db.RunInTx(context.Background(), nil, func(a *Adapter) error {
+				a.MustInsert(u)
+				panic("some panic")
+			})
+		}()
+		if found := db.MustGet(&txUser{ID: u.ID}); found {
+			t.Error("transaction changes should have been discarded")
+		}
+	})
+	t.Run("retry on deadlock", func(t *testing.T) {
+		u := &txUser{ID: RandomString(30), Name: RandomString(10)}
+		var attempts int
+		err := db.RunInTx(context.Background(), &TxOptions{RetryDelay: time.Millisecond}, func(a *Adapter) error {
+			attempts++
+			if _, err := a.Insert(u); err != nil {
+				return err
+			}
+			if attempts < 3 {
+				return &mysql.MySQLError{Number: 1213, Message: "Deadlock found when trying to get lock"}
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if attempts != 3 {
+			t.Errorf("expected 3 attempts, actual: %d", attempts)
+		}
+		if found := db.MustGet(&txUser{ID: u.ID}); !found {
+			t.Error("transaction changes should have been preserved")
+		}
+	})
+	t.Run("nested savepoint", func(t *testing.T) {
+		u1 := &txUser{ID: RandomString(30), Name: RandomString(10)}
+		u2 := &txUser{ID: RandomString(30), Name: RandomString(10)}
+		u3 := &txUser{ID: RandomString(30), Name: RandomString(10)}
+		innerErr := errors.New("inner error")
+		err := db.RunInTx(context.Background(), nil, func(a *Adapter) error {
+			if _, err := a.Insert(u1); err != nil {
+				return err
+			}
+			err := a.RunInTx(context.Background(), nil, func(a *Adapter) error {
+				if _, err := a.Insert(u2); err != nil {
+					return err
+				}
+				return innerErr
+			})
+			if err != innerErr {
+				return fmt.Errorf("inner error expected, actual: %v", err)
+			}
+			return a.RunInTx(context.Background(), nil, func(a *Adapter) error {
+				_, err := a.Insert(u3)
+				return err
+			})
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if found := db.MustGet(&txUser{ID: u1.ID}); !found {
+			t.Error("outer transaction changes should have been preserved")
+		}
+		if found := db.MustGet(&txUser{ID: u2.ID}); found {
+			t.Error("changes of failed savepoint should have been discarded")
+		}
+		if found := db.MustGet(&txUser{ID: u3.ID}); !found {
+			t.Error("changes of released savepoint should have been preserved")
+		}
+	})
+	t.Run("read only", func(t *testing.T) {
+		err := db.RunInTx(context.Background(), &TxOptions{ReadOnly: true, Isolation: sql.LevelReadCommitted}, func(a *Adapter) error {
+			_, err := a.Insert(&txUser{ID: RandomString(30)})
+			return err
+		})
+		if err == nil {
+			t.Error("insert in read only transaction expected to fail")
+		}
+	})
+}
@@ -0,0 +1,242 @@
+package mwear
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"time"
+)
+
+// DefaultBatchMaxBytes is the default estimated size of one insert query during batch insert,
+// matches mysql driver's default max_allowed_packet (4MB).
+const DefaultBatchMaxBytes = 4 << 20
+
+// BatchResult keeps combined result of all batch insert queries.
+type BatchResult struct {
+	// FirstInsertID is the auto increment id generated for the first inserted row.
+	FirstInsertID int64
+	// RowsAffected is the total number of inserted rows.
+	RowsAffected int64
+	// Chunks is the number of executed insert queries.
+	Chunks int
+}
+
+type batchConfig struct {
+	size     int
+	maxBytes int
+	inTx     bool
+}
+
+// BatchOption configures batch insert.
+type BatchOption func(cfg *batchConfig)
+
+// BatchSize sets max number of rows inserted per one query, cannot be more than LimitInsert.
+func BatchSize(size int) BatchOption {
+	return func(cfg *batchConfig) {
+		cfg.size = size
+	}
+}
+
+// BatchMaxBytes sets estimated max size of one insert query, should not exceed mysql max_allowed_packet.
+func BatchMaxBytes(maxBytes int) BatchOption {
+	return func(cfg *batchConfig) {
+		cfg.maxBytes = maxBytes
+	}
+}
+
+// BatchInTx runs all insert queries in one transaction, so either all rows are inserted or none.
+// If adapter already wraps a transaction, queries are executed in nested scope using savepoint.
+func BatchInTx() BatchOption {
+	return func(cfg *batchConfig) {
+		cfg.inTx = true
+	}
+}
+
+// MustInsertBatch ensures items are inserted without errors, panics othervise.
+func (a *Adapter) MustInsertBatch(items interface{}, opts ...BatchOption) *BatchResult {
+	res, err := a.InsertBatch(items, opts...)
+	if err != nil {
+		panic(err)
+	}
+	return res
+}
+
+// InsertBatch inserts slice of any length, splitting it into several insert queries
+// by number of rows (LimitInsert by default) and estimated query size (DefaultBatchMaxBytes by default).
+// items expected to be a slice of structs or struct pointers.
+//
+// Chunks are committed one by one, so in case of an error the result of already inserted chunks is returned
+// along with the error. With BatchInTx nil result is returned, since all rows are rolled back,
+// and auto increment ids set to the structs are reset.
+func (a *Adapter) InsertBatch(items interface{}, opts ...BatchOption) (*BatchResult, error) {
+	return a.InsertBatchContext(context.Background(), items, opts...)
+}
+
+// InsertBatchContext is the same as InsertBatch, but allows to cancel query using context.
+func (a *Adapter) InsertBatchContext(ctx context.Context, items interface{}, opts ...BatchOption) (*BatchResult, error) {
+	cfg := &batchConfig{
+		size:     LimitInsert,
+		maxBytes: DefaultBatchMaxBytes,
+	}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	if cfg.size <= 0 || cfg.size > LimitInsert {
+		return nil, fmt.Errorf("batch size should be between 1 and %d", LimitInsert)
+	}
+	if cfg.maxBytes <= 0 {
+		return nil, errors.New("batch max bytes should be greater than 0")
+	}
+
+	structPtrs, err := batchItems(items)
+	if err != nil {
+		return nil, err
+	}
+	if len(structPtrs) == 0 {
+		return nil, errors.New("nothing to insert")
+	}
+
+	if !cfg.inTx {
+		return a.insertChunks(ctx, cfg, structPtrs)
+	}
+
+	emptyIDs := emptyInsertIDs(structPtrs)
+	var res *BatchResult
+	err = a.RunInTx(ctx, &TxOptions{MaxRetries: -1}, func(txAdapter *Adapter) error {
+		var err error
+		res, err = txAdapter.insertChunks(ctx, cfg, structPtrs)
+		return err
+	})
+	if err != nil {
+		// rows are rolled back, so ids set by inserted chunks are reset.
+		for _, structPtr := range emptyIDs {
+			resetInsertID(structPtr)
+		}
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// emptyInsertIDs returns structs with empty primary key, auto increment ids may be set to them by insert.
+func emptyInsertIDs(structPtrs []interface{}) []interface{} {
+	var empty []interface{}
+	for _, structPtr := range structPtrs {
+		mod, err := parseModel(structPtr, true)
+		if err != nil || mod.IsCompositePK() {
+			continue
+		}
+		if mod.Fields[mod.PKPos].value(reflect.ValueOf(structPtr), false).IsZero() {
+			empty = append(empty, structPtr)
+		}
+	}
+	return empty
+}
+
+// resetInsertID sets primary key of the struct back to zero value.
+func resetInsertID(structPtr interface{}) {
+	mod, err := parseModel(structPtr, true)
+	if err != nil {
+		return
+	}
+	pk := mod.Fields[mod.PKPos].value(reflect.ValueOf(structPtr), true)
+	pk.Set(reflect.Zero(pk.Type()))
+}
+
+// insertChunks inserts structs chunk by chunk, in case of an error result of already inserted chunks
+// is returned along with the error.
+func (a *Adapter) insertChunks(ctx context.Context, cfg *batchConfig, structPtrs []interface{}) (*BatchResult, error) {
+	result := &BatchResult{}
+	insertChunk := func(chunk []interface{}) error {
+		res, err := a.InsertContext(ctx, chunk...)
+		if err != nil {
+			return fmt.Errorf("chunk (%d): %w", result.Chunks+1, err)
+		}
+		num, err := res.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("fail get number of affected rows: %w", err)
+		}
+		if result.Chunks == 0 {
+			// mysql returns the id generated for the first row of multi row insert.
+			if id, err := res.LastInsertId(); err == nil {
+				result.FirstInsertID = id
+			}
+		}
+		result.RowsAffected += num
+		result.Chunks++
+		return nil
+	}
+
+	var (
+		start     int
+		chunkSize int
+	)
+	for i, structPtr := range structPtrs {
+		rowSize := estimateRowSize(structPtr)
+		if i != start && (i-start >= cfg.size || chunkSize+rowSize > cfg.maxBytes) {
+			if err := insertChunk(structPtrs[start:i]); err != nil {
+				return result, err
+			}
+			start = i
+			chunkSize = 0
+		}
+		chunkSize += rowSize
+	}
+	if err := insertChunk(structPtrs[start:]); err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+// batchItems converts slice of structs or struct pointers to slice of struct pointers.
+func batchItems(items interface{}) ([]interface{}, error) {
+	rv := reflect.ValueOf(items)
+	if rv.Kind() != reflect.Slice {
+		return nil, errors.New("please pass a slice of structs or struct pointers to InsertBatch")
+	}
+
+	structPtrs := make([]interface{}, 0, rv.Len())
+	for i := 0; i < rv.Len(); i++ {
+		item := rv.Index(i)
+		if item.Kind() == reflect.Interface {
+			item = item.Elem()
+		}
+		switch {
+		case item.Kind() == reflect.Struct && item.CanAddr():
+			structPtrs = append(structPtrs, item.Addr().Interface())
+		case item.Kind() == reflect.Ptr && !item.IsNil() && item.Elem().Kind() == reflect.Struct:
+			structPtrs = append(structPtrs, item.Interface())
+		default:
+			return nil, fmt.Errorf("item (%d) of type (%s) is not a struct pointer", i, item.Type())
+		}
+	}
+
+	return structPtrs, nil
+}
+
+// estimateRowSize estimates the number of bytes one row takes in insert query.
+func estimateRowSize(structPtr interface{}) int {
+	mod, err := parseModel(structPtr, true)
+	if err != nil {
+		// invalid model is reported by insert, so size doesn't matter.
+		return 0
+	}
+	// placeholders, commas and braces
+	size := 2*len(mod.Fields) + 8
+	for _, val := range mod.getVals(reflect.ValueOf(structPtr), mod.Fields) {
+		switch v := val.(type) {
+		case string:
+			size += len(v)
+		case []byte:
+			size += len(v)
+		case time.Time:
+			size += 32
+		default:
+			size += 24
+		}
+	}
+
+	return size
+}
@@ -0,0 +1,228 @@
+package mwear
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// columnDef describes column definition of a field used to generate table schema.
+type columnDef struct {
+	// Type is a column data type, e.g. VARCHAR(255).
+	Type     string
+	Unsigned bool
+	Charset  string
+	// Default is a raw sql default value, empty if column has no default.
+	Default string
+	Comment string
+}
+
+// render returns column definition, extra is added before the comment (e.g. AUTO_INCREMENT PRIMARY KEY).
+func (c columnDef) render(notNull bool, extra string) string {
+	parts := []string{c.Type}
+	if c.Unsigned {
+		parts = append(parts, "UNSIGNED")
+	}
+	if c.Charset != "" {
+		parts = append(parts, "CHARACTER SET "+c.Charset)
+	}
+	if notNull {
+		parts = append(parts, "NOT NULL")
+	}
+	if c.Default != "" {
+		parts = append(parts, "DEFAULT "+c.Default)
+	}
+	if extra != "" {
+		parts = append(parts, extra)
+	}
+	if c.Comment != "" {
+		parts = append(parts, "COMMENT '"+strings.Replace(c.Comment, "'", "''", -1)+"'")
+	}
+	return strings.Join(parts, " ")
+}
+
+// isColumnOption checks whether mw tag option describes column definition, e.g. "size:1024".
+func isColumnOption(opt string) bool {
+	name := opt
+	if ind := strings.Index(opt, ":"); ind != -1 {
+		name = opt[:ind]
+	}
+	switch strings.TrimSpace(name) {
+	case "type", "size", "decimal", "unsigned", "default", "comment", "charset":
+		return true
+	}
+	return false
+}
+
+// baseColumn returns column definition of a field by its type.
+func (fi *field) baseColumn() (columnDef, error) {
+	if fi.Custom {
+		// custom types are stored as strings, unless column type is declared.
+		if nullType, ok := nullTypes[fi.ReflectType]; ok {
+			return columnDef{Type: nullType}, nil
+		}
+		return columnDef{Type: "VARCHAR(255)"}, nil
+	}
+	if fi.ReflectType.String() == timeType {
+		return columnDef{Type: "timestamp"}, nil
+	}
+
+	if isJSONKind(fi.ReflectKind) {
+		return columnDef{Type: mw_json}, nil
+	}
+	switch fi.ReflectKind {
+	case reflect.Int8, reflect.Int16:
+		return columnDef{Type: "SMALLINT", Default: "0"}, nil
+	case reflect.Uint8, reflect.Uint16:
+		return columnDef{Type: "SMALLINT", Unsigned: true, Default: "0"}, nil
+	case reflect.Int, reflect.Int32:
+		return columnDef{Type: "INT", Default: "0"}, nil
+	case reflect.Uint32:
+		return columnDef{Type: "INT", Unsigned: true, Default: "0"}, nil
+	case reflect.Int64:
+		return columnDef{Type: "BIGINT", Default: "0"}, nil
+	case reflect.Uint, reflect.Uint64:
+		return columnDef{Type: "BIGINT", Unsigned: true, Default: "0"}, nil
+	case reflect.Float32, reflect.Float64:
+		return columnDef{Type: "DOUBLE", Default: "0"}, nil
+	case reflect.String:
+		return columnDef{Type: "VARCHAR(255)", Default: "''"}, nil
+	case reflect.Bool:
+		return columnDef{Type: "tinyint(1)", Default: "0"}, nil
+	}
+	return columnDef{}, fmt.Errorf("unsupported type (%s)", fi.ReflectKind)
+}
+
+// setColumnOption applies column option of mw tag to column definition of the field.
+func (fi *field) setColumnOption(col *columnDef, opt string) error {
+	name, val := opt, ""
+	if ind := strings.Index(opt, ":"); ind != -1 {
+		name, val = strings.TrimSpace(opt[:ind]), strings.TrimSpace(opt[ind+1:])
+	}
+	if val == "" && name != "unsigned" {
+		return fmt.Errorf("value of (%s) option cannot be empty", name)
+	}
+
+	switch name {
+	case "type":
+		// implicit default may be invalid for declared type, so it should be set explicitly.
+		col.Type, col.Unsigned, col.Default = val, false, ""
+	case "size":
+		if fi.ReflectKind != reflect.String && !fi.Custom {
+			return fmt.Errorf("size option requires string field, got (%s)", fi.ReflectKind)
+		}
+		size, err := strconv.Atoi(val)
+		if err != nil || size <= 0 {
+			return fmt.Errorf("invalid size (%s)", val)
+		}
+		col.Type = "VARCHAR(" + val + ")"
+	case "decimal":
+		switch fi.ReflectKind {
+		case reflect.Float32, reflect.Float64:
+		default:
+			if !fi.Custom {
+				return fmt.Errorf("decimal option requires float field, got (%s)", fi.ReflectKind)
+			}
+		}
+		parts := strings.Split(val, ",")
+		for _, p := range parts {
+			if _, err := strconv.Atoi(strings.TrimSpace(p)); err != nil || len(parts) != 2 {
+				return fmt.Errorf("invalid decimal precision and scale (%s)", val)
+			}
+		}
+		col.Type = "DECIMAL(" + strings.TrimSpace(parts[0]) + "," + strings.TrimSpace(parts[1]) + ")"
+	case "unsigned":
+		switch fi.ReflectKind {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		default:
+			return fmt.Errorf("unsigned option requires integer field, got (%s)", fi.ReflectKind)
+		}
+		col.Unsigned = true
+	case "default":
+		col.Default = val
+	case "comment":
+		col.Comment = strings.Trim(val, "'")
+	case "charset":
+		if fi.ReflectKind != reflect.String && !fi.Custom {
+			return fmt.Errorf("charset option requires string field, got (%s)", fi.ReflectKind)
+		}
+		col.Charset = val
+	default:
+		return fmt.Errorf("invalid mw tag (%s)", opt)
+	}
+	return nil
+}
+
+// setColumn sets column definition of the field by its type and column options.
+func (fi *field) setColumn(opts []string) error {
+	col, err := fi.baseColumn()
+	if err != nil {
+		return err
+	}
+	for _, opt := range opts {
+		if err := fi.setColumnOption(&col, opt); err != nil {
+			return err
+		}
+	}
+	fi.column = col
+	fi.JSON = !fi.Custom && fi.ReflectType.String() != timeType && isJSONKind(fi.ReflectKind)
+	// JSON columns are declared without NOT NULL, so they may be stored as NULL.
+	fi.MWType = col.render(!fi.Nullable && col.Type != mw_json, "")
+	return nil
+}
+
+// setPKColumn sets column definition of primary key field, it is rendered once model is parsed,
+// since definition of composite key columns differs.
+func (fi *field) setPKColumn(opts []string) error {
+	var col columnDef
+	switch fi.ReflectKind {
+	case reflect.Int, reflect.Int32:
+		col.Type = "INT"
+	case reflect.Int64:
+		col.Type = "BIGINT"
+	case reflect.Uint32:
+		col.Type, col.Unsigned = "INT", true
+	case reflect.Uint, reflect.Uint64:
+		col.Type, col.Unsigned = "BIGINT", true
+	case reflect.String:
+		col.Type = "VARCHAR(255)"
+	default:
+		return fmt.Errorf("unsupported type (%s) for primary key", fi.ReflectKind)
+	}
+	for _, opt := range opts {
+		if strings.HasPrefix(opt, "default") || strings.HasPrefix(opt, "decimal") {
+			return errors.New("default and decimal options cannot be used for primary key")
+		}
+		if err := fi.setColumnOption(&col, opt); err != nil {
+			return err
+		}
+	}
+	fi.column = col
+	return nil
+}
+
+// renderPK sets column types of primary key fields: single key is declared inline,
+// columns of composite key are declared without PRIMARY KEY and AUTO_INCREMENT.
+func (mod *model) renderPK() {
+	for _, f := range mod.PKFields {
+		var extra string
+		if !mod.IsCompositePK() {
+			extra = "PRIMARY KEY"
+			if f.ReflectKind != reflect.String {
+				extra = "AUTO_INCREMENT PRIMARY KEY"
+			}
+		}
+		f.MWType = f.column.render(true, extra)
+	}
+}
+
+func isJSONKind(kind reflect.Kind) bool {
+	switch kind {
+	case reflect.Array, reflect.Slice, reflect.Map, reflect.Struct:
+		return true
+	}
+	return false
+}
@@ -10,9 +10,6 @@ const (
 	connMaxLifetime = 60 * time.Second
 )
 
-// TODO is this being used? (grep)
-var debugEnabled bool
-
 // DB is a wrapper around standard sql db that also wraps common sql opperations.
 type DB struct {
 	DB *sql.DB
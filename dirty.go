@@ -0,0 +1,110 @@
+package mwear
+
+import (
+	"reflect"
+)
+
+// Tracked enables dirty tracking when embedded into a model:
+//
+//	type User struct {
+//		mw.Tracked
+//		ID   string
+//		Name string
+//	}
+//
+// Values of model fields are remembered when it is loaded by Get, Select or Iterate, inserted or updated,
+// so Update writes only the columns which have been changed since then.
+// Models which weren't loaded (no snapshot) are updated fully.
+type Tracked struct {
+	snapshot map[string]interface{}
+}
+
+// ResetSnapshot forgets loaded values of the model, so the next Update writes all columns.
+func (t *Tracked) ResetSnapshot() {
+	t.snapshot = nil
+}
+
+var trackedType = reflect.TypeOf(Tracked{})
+
+// tracked returns dirty tracking data of the row, nil if model doesn't embed Tracked.
+func (mod *model) tracked(rowModel reflect.Value) *Tracked {
+	if mod.TrackedPos == nil {
+		return nil
+	}
+	return valueByIndex(rowModel, mod.TrackedPos, true).Addr().Interface().(*Tracked)
+}
+
+// takeSnapshot remembers current values of row fields for dirty tracking.
+func (mod *model) takeSnapshot(rowModel reflect.Value) {
+	t := mod.tracked(rowModel)
+	if t == nil {
+		return
+	}
+	vals := mod.getVals(rowModel, mod.Fields)
+	// new map is created each time, since copies of the model share the previous one.
+	snapshot := make(map[string]interface{}, len(vals))
+	for i, f := range mod.Fields {
+		snapshot[f.MWName] = vals[i]
+	}
+	t.snapshot = snapshot
+}
+
+// restoreField sets field at position pos in Fields back to its snapshot value.
+func (mod *model) restoreField(rowModel reflect.Value, pos int) {
+	t := mod.tracked(rowModel)
+	if t == nil || t.snapshot == nil {
+		return
+	}
+	if val, ok := t.snapshot[mod.Fields[pos].MWName]; ok && val != nil {
+		mod.Fields[pos].value(rowModel, true).Set(reflect.ValueOf(val))
+	}
+}
+
+// updateFields returns non primary key fields to be updated: specified columns, fields changed since
+// the snapshot of tracked model, or all fields. Updated timestamp and version fields are included
+// only along with other fields, so empty result means there is nothing to update.
+func (mod *model) updateFields(rowModel reflect.Value, columns []string) ([]*field, error) {
+	fields, err := mod.GetFieldsNoPK(columns)
+	if err != nil {
+		return nil, err
+	}
+	if len(columns) == 0 {
+		dirty, ok := mod.dirtyFields(rowModel, fields)
+		if !ok {
+			return fields, nil
+		}
+		fields = dirty
+	}
+
+	res := make([]*field, 0, len(fields)+2)
+	for _, f := range fields {
+		if f != mod.fieldByPos(mod.UpdatedPos) && f != mod.fieldByPos(mod.VersionPos) {
+			res = append(res, f)
+		}
+	}
+	if len(res) == 0 {
+		return nil, nil
+	}
+	for _, pos := range []int{mod.UpdatedPos, mod.VersionPos} {
+		if pos != -1 {
+			res = append(res, mod.fieldByPos(pos))
+		}
+	}
+	return res, nil
+}
+
+// dirtyFields returns fields changed since the snapshot, ok is false if there is no snapshot.
+func (mod *model) dirtyFields(rowModel reflect.Value, fields []*field) (dirty []*field, ok bool) {
+	t := mod.tracked(rowModel)
+	if t == nil || t.snapshot == nil {
+		return nil, false
+	}
+	vals := mod.getVals(rowModel, fields)
+	dirty = make([]*field, 0, len(fields))
+	for i, f := range fields {
+		if !reflect.DeepEqual(vals[i], t.snapshot[f.MWName]) {
+			dirty = append(dirty, f)
+		}
+	}
+	return dirty, true
+}
@@ -0,0 +1,185 @@
+package mwear
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// Operations reported by QueryError and QueryEvent.
+const (
+	OpInsert      = "insert"
+	OpUpsert      = "upsert"
+	OpSelect      = "select"
+	OpGet         = "get"
+	OpCount       = "count"
+	OpUpdate      = "update"
+	OpDelete      = "delete"
+	OpSavepoint   = "savepoint"
+	OpCreateTable = "create_table"
+	// OpExec is the operation of raw sql queries, like migrations.
+	OpExec = "exec"
+)
+
+// Sentinel errors mysql errors are classified into, can be checked using errors.Is:
+//
+//	if errors.Is(err, mw.ErrForeignKeyViolation) {
+//		...
+//	}
+var (
+	ErrUniqueViolation     = errors.New("mw: unique violation")
+	ErrTableExists         = errors.New("mw: table already exists")
+	ErrDeadlock            = errors.New("mw: deadlock")
+	ErrLockWaitTimeout     = errors.New("mw: lock wait timeout")
+	ErrForeignKeyViolation = errors.New("mw: foreign key violation")
+	ErrNotNullViolation    = errors.New("mw: not null violation")
+	ErrDataTooLong         = errors.New("mw: data too long")
+	ErrUnknownColumn       = errors.New("mw: unknown column")
+	ErrReadOnly            = errors.New("mw: read only")
+	ErrConnectionLost      = errors.New("mw: connection lost")
+)
+
+// ErrStaleObject is returned by Update of a model with version field, in case the row
+// was changed or deleted since the model was loaded, can be checked using errors.Is.
+var ErrStaleObject = errors.New("mw: stale object")
+
+// mysqlErrors maps mysql error numbers to sentinel errors.
+var mysqlErrors = map[uint16]error{
+	1062: ErrUniqueViolation,     // duplicate entry
+	1050: ErrTableExists,         // table already exists
+	1213: ErrDeadlock,            // deadlock found when trying to get lock
+	1205: ErrLockWaitTimeout,     // lock wait timeout exceeded
+	1451: ErrForeignKeyViolation, // cannot delete or update a parent row
+	1452: ErrForeignKeyViolation, // cannot add or update a child row
+	1048: ErrNotNullViolation,    // column cannot be null
+	1364: ErrNotNullViolation,    // field doesn't have a default value
+	1406: ErrDataTooLong,         // data too long for column
+	1054: ErrUnknownColumn,       // unknown column
+	1290: ErrReadOnly,            // server is running with the --read-only option
+	1792: ErrReadOnly,            // cannot execute statement in a read only transaction
+	1836: ErrReadOnly,            // running in read-only mode
+	2006: ErrConnectionLost,      // server has gone away
+	2013: ErrConnectionLost,      // lost connection during query
+}
+
+// QueryError is returned by adapter methods in case query fails.
+// It keeps the original driver error, which can be accessed using errors.As:
+//
+//	var mysqlErr *mysql.MySQLError
+//	if errors.As(err, &mysqlErr) {
+//		...
+//	}
+type QueryError struct {
+	// Op is the operation, like insert or select.
+	Op string
+	// Table is the name of the model table.
+	Table string
+	// SQL is the executed query.
+	SQL string
+	// Err is the original error.
+	Err error
+}
+
+func (e *QueryError) Error() string {
+	if e.Table == "" {
+		return fmt.Sprintf("%s error: %v", e.Op, e.Err)
+	}
+	return fmt.Sprintf("%s error (%s): %v", e.Op, e.Table, e.Err)
+}
+
+// Unwrap returns the original error.
+func (e *QueryError) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether the original error is classified as target sentinel error.
+func (e *QueryError) Is(target error) bool {
+	return target != nil && ClassifyError(e.Err) == target
+}
+
+func newQueryError(op, table, sqlStr string, err error) error {
+	if err == nil {
+		return nil
+	}
+	var queryErr *QueryError
+	if errors.As(err, &queryErr) {
+		return err
+	}
+
+	return &QueryError{Op: op, Table: table, SQL: sqlStr, Err: err}
+}
+
+// ClassifyError returns sentinel error the err is classified as, or nil if error is unknown.
+func ClassifyError(err error) error {
+	if err == nil {
+		return nil
+	}
+	var mysqlError *mysql.MySQLError
+	if errors.As(err, &mysqlError) {
+		return mysqlErrors[mysqlError.Number]
+	}
+	if errors.Is(err, mysql.ErrInvalidConn) || errors.Is(err, driver.ErrBadConn) {
+		return ErrConnectionLost
+	}
+
+	return nil
+}
+
+// IsUniqueViolationError checks whether an error is duplicate entry error.
+func IsUniqueViolationError(err error) bool {
+	return ClassifyError(err) == ErrUniqueViolation
+}
+
+// IsTableExistsError checks whether an error is table already exists error.
+func IsTableExistsError(err error) bool {
+	return ClassifyError(err) == ErrTableExists
+}
+
+// IsDeadlockError checks whether an error is deadlock error.
+func IsDeadlockError(err error) bool {
+	return ClassifyError(err) == ErrDeadlock
+}
+
+// IsLockWaitTimeoutError checks whether an error is lock wait timeout error.
+func IsLockWaitTimeoutError(err error) bool {
+	return ClassifyError(err) == ErrLockWaitTimeout
+}
+
+// IsForeignKeyViolationError checks whether an error is foreign key constraint error,
+// either on deleting referenced parent row or on adding child row without parent.
+func IsForeignKeyViolationError(err error) bool {
+	return ClassifyError(err) == ErrForeignKeyViolation
+}
+
+// IsNotNullViolationError checks whether an error is caused by NULL or missing value of NOT NULL column.
+func IsNotNullViolationError(err error) bool {
+	return ClassifyError(err) == ErrNotNullViolation
+}
+
+// IsDataTooLongError checks whether an error is caused by value exceeding column size.
+func IsDataTooLongError(err error) bool {
+	return ClassifyError(err) == ErrDataTooLong
+}
+
+// IsUnknownColumnError checks whether an error is unknown column error.
+func IsUnknownColumnError(err error) bool {
+	return ClassifyError(err) == ErrUnknownColumn
+}
+
+// IsReadOnlyError checks whether an error is caused by writing to read only server (e.g. replica)
+// or in read only transaction.
+func IsReadOnlyError(err error) bool {
+	return ClassifyError(err) == ErrReadOnly
+}
+
+// IsStaleObjectError checks whether an error is caused by updating outdated version of a model.
+func IsStaleObjectError(err error) bool {
+	return errors.Is(err, ErrStaleObject)
+}
+
+// IsConnectionLostError checks whether an error is caused by broken connection to server.
+func IsConnectionLostError(err error) bool {
+	return ClassifyError(err) == ErrConnectionLost
+}
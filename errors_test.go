@@ -0,0 +1,61 @@
+package mwear
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+func TestClassifyError(t *testing.T) {
+	cases := []struct {
+		err      error
+		expected error
+		is       func(err error) bool
+	}{
+		{&mysql.MySQLError{Number: 1062}, ErrUniqueViolation, IsUniqueViolationError},
+		{&mysql.MySQLError{Number: 1050}, ErrTableExists, IsTableExistsError},
+		{&mysql.MySQLError{Number: 1213}, ErrDeadlock, IsDeadlockError},
+		{&mysql.MySQLError{Number: 1205}, ErrLockWaitTimeout, IsLockWaitTimeoutError},
+		{&mysql.MySQLError{Number: 1451}, ErrForeignKeyViolation, IsForeignKeyViolationError},
+		{&mysql.MySQLError{Number: 1452}, ErrForeignKeyViolation, IsForeignKeyViolationError},
+		{&mysql.MySQLError{Number: 1048}, ErrNotNullViolation, IsNotNullViolationError},
+		{&mysql.MySQLError{Number: 1406}, ErrDataTooLong, IsDataTooLongError},
+		{&mysql.MySQLError{Number: 1054}, ErrUnknownColumn, IsUnknownColumnError},
+		{&mysql.MySQLError{Number: 1290}, ErrReadOnly, IsReadOnlyError},
+		{mysql.ErrInvalidConn, ErrConnectionLost, IsConnectionLostError},
+		{driver.ErrBadConn, ErrConnectionLost, IsConnectionLostError},
+		{&mysql.MySQLError{Number: 1064}, nil, nil},
+		{errors.New("some error"), nil, nil},
+	}
+
+	for _, c := range cases {
+		queryErr := newQueryError(OpInsert, "user", "INSERT INTO `user` ...", c.err)
+		if got := ClassifyError(queryErr); got != c.expected {
+			t.Errorf("error (%v) expected to be classified as (%v), got (%v)", c.err, c.expected, got)
+		}
+		if c.expected == nil {
+			continue
+		}
+		if !errors.Is(queryErr, c.expected) {
+			t.Errorf("errors.Is(%v, %v) expected to be true", queryErr, c.expected)
+		}
+		if !c.is(c.err) || !c.is(fmt.Errorf("wrapped: %w", queryErr)) {
+			t.Errorf("error (%v) expected to be (%v)", c.err, c.expected)
+		}
+	}
+
+	queryErr := newQueryError(OpDelete, "user", "DELETE FROM `user` ...", &mysql.MySQLError{Number: 1451})
+	if errors.Is(queryErr, ErrUniqueViolation) {
+		t.Errorf("foreign key violation shouldn't be unique violation")
+	}
+	var mysqlErr *mysql.MySQLError
+	if !errors.As(queryErr, &mysqlErr) || mysqlErr.Number != 1451 {
+		t.Errorf("original mysql error expected to be kept")
+	}
+	if newQueryError(OpSelect, "account", "", queryErr) != queryErr {
+		t.Errorf("query error shouldn't be wrapped twice")
+	}
+}
@@ -4,8 +4,12 @@ import (
 	"bytes"
 	"crypto/md5"
 	"encoding/hex"
+	"fmt"
+	"go/token"
+	"strings"
 	"sync"
 	"text/template"
+	"unicode"
 )
 
 var (
@@ -13,7 +17,16 @@ var (
 	mx    sync.Mutex
 )
 
-func renderTemplate(mod interface{}, sqlTemplate string) string {
+// mustRenderTemplate is the same as renderTemplate, but panics in case of an error.
+func mustRenderTemplate(mod interface{}, sqlTemplate string) string {
+	res, err := renderTemplate(mod, sqlTemplate)
+	if err != nil {
+		panic(err)
+	}
+	return res
+}
+
+func renderTemplate(mod interface{}, sqlTemplate string) (string, error) {
 	buff := &bytes.Buffer{}
 
 	hasher := md5.New()
@@ -31,7 +44,7 @@ func renderTemplate(mod interface{}, sqlTemplate string) string {
 	} else {
 		tmpl, err = template.New("sql").Funcs(funcMap).Parse(sqlTemplate)
 		if err != nil {
-			panic(err)
+			return "", fmt.Errorf("fail parse template: %v", err)
 		}
 		mx.Lock()
 		tmpls[hash] = tmpl
@@ -40,12 +53,14 @@ func renderTemplate(mod interface{}, sqlTemplate string) string {
 
 	err = tmpl.Execute(buff, mod)
 	if err != nil {
-		panic(err)
+		return "", fmt.Errorf("fail render template: %v", err)
 	}
-	return buff.String()
+	return buff.String(), nil
 }
 
-const insertTemplate = `
+const insertTemplate = insertBaseTemplate + ";\n"
+
+const insertBaseTemplate = `
 INSERT INTO ` + "`{{.model.TableName}}`" + `(
 	{{ range $i, $e := .model.Fields }}
 	{{- if eq $i (minus (len $.model.Fields) 1) }}{{$e.MWNameQuoted}}
@@ -59,7 +74,14 @@ INSERT INTO ` + "`{{.model.TableName}}`" + `(
 		{{end }}
 	){{- if ne $itemNum (minus (len $.Items) 1) }},{{- end }}
 	{{end -}}
-;
+`
+
+const upsertTemplate = insertBaseTemplate + `ON DUPLICATE KEY UPDATE
+	{{ range $i, $e := .updates }}
+	{{- if eq $i (minus (len $.updates) 1) }}{{$e.Column}} = {{$e.Value}}
+	{{- else -}}{{$e.Column}} = {{$e.Value}},
+	{{end -}}
+{{- end }};
 `
 const selectBaseTemplate = `SELECT
 	{{ range $i, $e := .fields }}
@@ -98,6 +120,25 @@ var funcMap = template.FuncMap{
 	"minus": minus,
 	"plus":  plus,
 	"mul":   multiply,
+	"param": paramName,
+}
+
+// paramName returns function parameter name for a field go name, e.g. UserID is userID, ID is id.
+func paramName(goName string) string {
+	runes := []rune(goName)
+	upper := 0
+	for upper < len(runes) && unicode.IsUpper(runes[upper]) {
+		upper++
+	}
+	// keep the first letter of the next word, like URL in URLPath.
+	if upper > 1 && upper < len(runes) {
+		upper--
+	}
+	name := strings.ToLower(string(runes[:upper])) + string(runes[upper:])
+	if token.Lookup(name).IsKeyword() || name == "db" {
+		name += "Val"
+	}
+	return name
 }
 
 func minus(a, b int) int {
@@ -135,6 +176,15 @@ CREATE TABLE ` + "`{{.TableName}}`" + `(
 	{{- else -}} {{$e.MWNameQuoted}} {{$e.MWType}},
 	{{end -}}
 {{- end }}
+{{- if .IsCompositePK }},
+	PRIMARY KEY ({{.PKColumnsQuoted}})
+{{- end }}
+{{- range .Indexes }},
+	{{.Definition}}
+{{- end }}
+{{- range .ForeignKeys }},
+	{{.Definition}}
+{{- end }}
 );
 `
 
@@ -147,8 +197,8 @@ func New{{.StructName}}() *{{.StructName}}{
 	return &{{.StructName}}{}
 }
 
-func Get{{.StructName}}(db *mw.DB, id {{ .GetPKField.ReflectType.String }}) (*{{.StructName}}, error) {
-	{{.ShortName}} := &{{.StructName}}{ID: id}
+func Get{{.StructName}}(db *mw.DB {{- range .PKFields }}, {{ param .GoName }} {{ .ReflectType.String }}{{ end }}) (*{{.StructName}}, error) {
+	{{.ShortName}} := &{{.StructName}}{ {{- range $i, $f := .PKFields }}{{ if $i }}, {{ end }}{{ $f.GoName }}: {{ param $f.GoName }}{{ end -}} }
 	found, err := db.Get({{.ShortName}})
 	if err != nil {
 		return nil, err
@@ -160,30 +210,13 @@ func Get{{.StructName}}(db *mw.DB, id {{ .GetPKField.ReflectType.String }}) (*{{
 }
 
 func ({{.ShortName}} *{{.StructName}}) Insert(db *mw.DB) error {
-	{{.ShortName}}.Created = time.Now().UTC()
-	{{.ShortName}}.Updated = time.Now().UTC()
-	res, err := db.Insert({{.ShortName}})
-	if err != nil {
+	if _, err := db.Insert({{.ShortName}}); err != nil {
 		return err
 	}
-	{{ if .IsIntPK -}}
-	id, err := res.LastInsertId()
-	if err != nil {
-		return fmt.Errorf("fail get last id: %v", err)
-	}
-	{{ if eq .GetPKKind 2 -}}
-	{{.ShortName}}.ID = int(id)
-	{{ else if eq .GetPKKind 5 -}}
-	{{.ShortName}}.ID = int32(id)
-	{{ else -}}
-	{{.ShortName}}.ID = id
-	{{ end -}}
-	{{ end -}}
 	return nil
 }
 
 func ({{.ShortName}} *{{.StructName}}) Update(db *mw.DB) error {
-	{{.ShortName}}.Updated = time.Now().UTC()
 	if err := db.Update({{.ShortName}}); err != nil {
 		return err
 	}
@@ -226,27 +259,27 @@ func Test{{.StructName}}CRUD(t *testing.T){
 	}
 
 	// Make sure we can get the newly inserted object
-	{{.ShortName}}2, err := Get{{.StructName}}(db, {{.ShortName}}.ID)
+	{{.ShortName}}2, err := Get{{.StructName}}(db {{- range .PKFields }}, {{ $.ShortName }}.{{ .GoName }}{{ end }})
 	if err != nil {
-		log.Fatalf("fail get item {{ if .IsIntPK }}%d{{else}}%s{{end}}: %v", {{.ShortName}}.ID, err)
+		log.Fatalf("fail get item ({{ range $i, $f := .PKFields }}{{ if $i }}, {{ end }}%v{{ end }}): %v", {{ range .PKFields }}{{ $.ShortName }}.{{ .GoName }}, {{ end }}err)
 	}
 	if {{.ShortName}}2 == nil {
-		t.Fatalf("Didnt find newly inserted row with ID {{ if .IsIntPK }}%d{{else}}%s{{end}}", {{.ShortName}}.ID)
+		t.Fatalf("Didnt find newly inserted row with primary key ({{ range $i, $f := .PKFields }}{{ if $i }}, {{ end }}%v{{ end }})", {{ range $i, $f := .PKFields }}{{ if $i }}, {{ end }}{{ $.ShortName }}.{{ $f.GoName }}{{ end }})
 	}
 	// Make some changes to {{.ShortName}} here
 
 
 	if err := {{.ShortName}}.Update(db); err != nil {
-		log.Fatalf("id ({{ if .IsIntPK }}%d{{else}}%s{{end}}): update failed: %v", {{.ShortName}}.ID, err)
+		log.Fatalf("primary key ({{ range $i, $f := .PKFields }}{{ if $i }}, {{ end }}%v{{ end }}): update failed: %v", {{ range .PKFields }}{{ $.ShortName }}.{{ .GoName }}, {{ end }}err)
 	}
 
 	// Make sure those changes took effect
-	{{.ShortName}}3, err := Get{{.StructName}}(db, {{.ShortName}}.ID)
+	{{.ShortName}}3, err := Get{{.StructName}}(db {{- range .PKFields }}, {{ $.ShortName }}.{{ .GoName }}{{ end }})
 	if err != nil {
-		log.Fatalf("fail get item {{ if .IsIntPK }}%d{{else}}%s{{end}}: %v", {{.ShortName}}3.ID, err)
+		log.Fatalf("fail get item ({{ range $i, $f := .PKFields }}{{ if $i }}, {{ end }}%v{{ end }}): %v", {{ range .PKFields }}{{ $.ShortName }}.{{ .GoName }}, {{ end }}err)
 	}
 	if {{.ShortName}}3 == nil {
-		t.Fatalf("Missing row 3 ID {{ if .IsIntPK }}%d{{else}}%s{{end}}", {{.ShortName}}3.ID)
+		t.Fatalf("Missing row 3 with primary key ({{ range $i, $f := .PKFields }}{{ if $i }}, {{ end }}%v{{ end }})", {{ range $i, $f := .PKFields }}{{ if $i }}, {{ end }}{{ $.ShortName }}.{{ $f.GoName }}{{ end }})
 	}
 
 	// Compare props
@@ -279,6 +312,53 @@ func main() {
 }
 `
 
+const diffTemplate = `
+// -------------------------------------------- //
+// AUTO GENERATED - Place in a temporary go file
+// -------------------------------------------- //
+
+package main
+
+import (
+	"database/sql"
+	"log"
+	"os"
+
+	mw "github.com/cliqueinc/mysql-wear"
+	_ "github.com/go-sql-driver/mysql"
+
+	models "{{.Package}}"
+)
+
+func main() {
+	// DB_DSN is a data source name, e.g. user:password@tcp(127.0.0.1:3306)/dbname?parseTime=true
+	con, err := sql.Open("mysql", os.Getenv("DB_DSN"))
+	if err != nil {
+		log.Fatalf("fail open db connection: %v", err)
+	}
+	defer con.Close()
+	if err := mw.RegisterMigrationPath(os.Getenv("DB_MIGRATION_PATH")); err != nil {
+		log.Fatalf("fail register migration path: %v", err)
+	}
+
+	diff, err := mw.DiffSchema(mw.New(con),
+	{{- range $i, $name := .StructNames }}{{ if $i }},{{ end }} &models.{{ $name }}{}{{ end -}}
+	)
+	if err != nil {
+		log.Fatalf("fail diff schema: %v", err)
+	}
+	if diff.Empty() {
+		log.Println("schema is up to date")
+		return
+	}
+	version, err := diff.WriteMigration()
+	if err != nil {
+		log.Fatalf("fail write migration: %v", err)
+	}
+	log.Printf("migration (%s) created:\n%s", version, diff.UpSQL())
+}
+`
+
 // ------------------------------------------------------------------------- //
 // Generate functions
 // ------------------------------------------------------------------------- //
@@ -287,26 +367,43 @@ func main() {
 // other generation. There is not an easy way to dynamically generate things
 // from structs like in other languages
 func GenerateInit(structName, shortName string) string {
-	return renderTemplate(map[string]string{"StructName": structName,
+	return mustRenderTemplate(map[string]string{"StructName": structName,
 		"ShortName": shortName}, initTemplate)
 }
 
+// GenerateDiff generates a program writing migration of differences between models of the package
+// and the database schema, models cannot be loaded dynamically, so it's used by mwcmd gen diff.
+func GenerateDiff(pkgPath string, structNames ...string) string {
+	return mustRenderTemplate(map[string]interface{}{"Package": pkgPath, "StructNames": structNames}, diffTemplate)
+}
+
 // Get the create SQL statement which is generally the most useful since we need to
 // add this to a schema migration file.
 func GenerateModel(structPtr interface{}, shortName string) string {
-	mod := parseModel(structPtr, true)
+	mod := mustParseModel(structPtr, true)
 	mod.ShortName = shortName
-	return renderTemplate(mod, modelTemplate)
+	return mustRenderTemplate(mod, modelTemplate)
 }
 
 func GenerateModelTest(structPtr interface{}, shortName string) string {
-	mod := parseModel(structPtr, true)
+	mod := mustParseModel(structPtr, true)
 	mod.ShortName = shortName
-	return renderTemplate(mod, modelTestTemplate)
+	return mustRenderTemplate(mod, modelTestTemplate)
 }
 
-// GenerateSchema generates table schema from struct model.
+// GenerateSchema generates table schema from struct model, panics in case model is invalid.
 func GenerateSchema(structPtr interface{}) string {
-	mod := parseModel(structPtr, true)
+	schema, err := generateSchema(structPtr)
+	if err != nil {
+		panic(err)
+	}
+	return schema
+}
+
+func generateSchema(structPtr interface{}) (string, error) {
+	mod, err := parseModel(structPtr, true)
+	if err != nil {
+		return "", err
+	}
 	return renderTemplate(mod, createTableTemplate)
 }
@@ -1,12 +1,14 @@
 package mwear_test
 
 import (
+	"database/sql"
 	"fmt"
 	"strings"
 	"testing"
 	"time"
 
 	mw "github.com/cliqueinc/mysql-wear"
+	"github.com/go-sql-driver/mysql"
 )
 
 // Example email struct used for generating sql, model, test
@@ -19,6 +21,22 @@ type Email struct {
 	Updated         time.Time
 }
 
+// UserPost is used to test index and foreign key declarations.
+type UserPost struct {
+	ID      int
+	UserID  int       `mw:"fk:user.id,on_delete:cascade,index:idx_user_created,order:1"`
+	Slug    string    `mw:"size:64,unique"`
+	Title   string    `mw:"index"`
+	Body    string    `mw:"type:TEXT"`
+	Created time.Time `mw:"created,index:idx_user_created,order:2"`
+}
+
+func (p *UserPost) Indexes() []mw.Index {
+	return []mw.Index{
+		{Name: "idx_title_body", Columns: []string{"title", "body(100)"}},
+	}
+}
+
 func ExampleGenerateModel() {
 	fmt.Println(mw.GenerateSchema(&Email{}))
 	fmt.Println(mw.GenerateModel(&Email{}, "email"))
@@ -63,4 +81,156 @@ func TestGenerateSchema(ts *testing.T) {
 		schema := mw.GenerateSchema(&UserProfile4{})
 		assertContains(t, schema, "`id` VARCHAR(255) NOT NULL PRIMARY KEY")
 	})
+
+	ts.Run("Created and updated timestamps", func(t *testing.T) {
+		type UserProfile5 struct {
+			ID      string
+			Created time.Time `mw:"created"`
+			Updated time.Time `mw:"updated"`
+		}
+		schema := mw.GenerateSchema(&UserProfile5{})
+		assertContains(t, schema, "`created` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,")
+		assertContains(t, schema, "`updated` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP")
+	})
+
+	ts.Run("Version", func(t *testing.T) {
+		type UserProfile6 struct {
+			ID      string
+			Version int `mw:"version"`
+		}
+		schema := mw.GenerateSchema(&UserProfile6{})
+		assertContains(t, schema, "`version` INT NOT NULL DEFAULT 0")
+
+		type UserProfile6a struct {
+			ID      string
+			Version int64 `mw:"version"`
+		}
+		schema = mw.GenerateSchema(&UserProfile6a{})
+		assertContains(t, schema, "`version` BIGINT NOT NULL DEFAULT 0")
+	})
+
+	ts.Run("Unsigned primary key", func(t *testing.T) {
+		type UserProfile6b struct {
+			ID   uint64
+			Name string
+		}
+		schema := mw.GenerateSchema(&UserProfile6b{})
+		assertContains(t, schema, "`id` BIGINT UNSIGNED NOT NULL AUTO_INCREMENT PRIMARY KEY,")
+	})
+
+	ts.Run("Unsigned columns", func(t *testing.T) {
+		type UserProfile6c struct {
+			ID     string
+			Small  uint16
+			Count  uint32
+			Big    uint
+			Amount uint64
+		}
+		schema := mw.GenerateSchema(&UserProfile6c{})
+		assertContains(t, schema, "`small` SMALLINT UNSIGNED NOT NULL DEFAULT 0,")
+		assertContains(t, schema, "`count` INT UNSIGNED NOT NULL DEFAULT 0,")
+		assertContains(t, schema, "`big` BIGINT UNSIGNED NOT NULL DEFAULT 0,")
+		assertContains(t, schema, "`amount` BIGINT UNSIGNED NOT NULL DEFAULT 0")
+	})
+
+	ts.Run("Composite primary key", func(t *testing.T) {
+		type UserProfile7 struct {
+			UserID  string `mw:"pk"`
+			GroupID int    `mw:"pk"`
+			Role    string
+		}
+		schema := mw.GenerateSchema(&UserProfile7{})
+		assertContains(t, schema, "`user_id` VARCHAR(255) NOT NULL,")
+		assertContains(t, schema, "`group_id` INT NOT NULL,")
+		assertContains(t, schema, "PRIMARY KEY (`user_id`, `group_id`)")
+
+		model := mw.GenerateModel(&UserProfile7{}, "up")
+		assertContains(t, model, "func GetUserProfile7(db *mw.DB, userID string, groupID int) (*UserProfile7, error) {")
+		assertContains(t, model, "up := &UserProfile7{UserID: userID, GroupID: groupID}")
+
+		modelTest := mw.GenerateModelTest(&UserProfile7{}, "up")
+		assertContains(t, modelTest, "up2, err := GetUserProfile7(db, up.UserID, up.GroupID)")
+		assertContains(t, modelTest, `log.Fatalf("fail get item (%v, %v): %v", up.UserID, up.GroupID, err)`)
+		if strings.Contains(modelTest, ".ID") {
+			t.Errorf("model test of composite key shouldn't use ID field:\n%s", modelTest)
+		}
+	})
+
+	ts.Run("Custom types", func(t *testing.T) {
+		type UserProfile8 struct {
+			ID      string
+			Nick    sql.NullString
+			Visited mysql.NullTime
+			Balance sql.NullFloat64 `mw:"type:DECIMAL(12,2)"`
+			Rating  int             `mw:"type:TINYINT,nullable"`
+		}
+		schema := mw.GenerateSchema(&UserProfile8{})
+		assertContains(t, schema, "`nick` VARCHAR(255),")
+		assertContains(t, schema, "`visited` timestamp,")
+		assertContains(t, schema, "`balance` DECIMAL(12,2),")
+		assertContains(t, schema, "`rating` TINYINT\n")
+	})
+
+	ts.Run("Pointer fields", func(t *testing.T) {
+		type UserProfile9 struct {
+			ID       string
+			Nick     *string
+			Age      *int64
+			Birthday *time.Time
+		}
+		schema := mw.GenerateSchema(&UserProfile9{})
+		assertContains(t, schema, "`nick` VARCHAR(255) DEFAULT '',")
+		assertContains(t, schema, "`age` BIGINT DEFAULT 0,")
+		assertContains(t, schema, "`birthday` timestamp\n")
+	})
+
+	ts.Run("Embedded struct", func(t *testing.T) {
+		type BaseModel struct {
+			ID      int
+			Created time.Time `mw:"created"`
+		}
+		type UserProfile10 struct {
+			BaseModel
+			Name string
+		}
+		schema := mw.GenerateSchema(&UserProfile10{})
+		assertContains(t, schema, "`id` INT NOT NULL AUTO_INCREMENT PRIMARY KEY,")
+		assertContains(t, schema, "`created` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,")
+		assertContains(t, schema, "`name` VARCHAR(255) NOT NULL DEFAULT ''")
+	})
+
+	ts.Run("Column options", func(t *testing.T) {
+		type UserProfile11 struct {
+			ID      string  `mw:"pk,size:36"`
+			Bio     string  `mw:"type:TEXT,nullable"`
+			Nick    string  `mw:"size:64,charset:utf8mb4,default:'anon'"`
+			Price   float64 `mw:"decimal:12,2"`
+			Age     int     `mw:"unsigned,comment:'age, in years'"`
+			Views   int64
+			Counter uint64
+		}
+		schema := mw.GenerateSchema(&UserProfile11{})
+		assertContains(t, schema, "`id` VARCHAR(36) NOT NULL PRIMARY KEY,")
+		assertContains(t, schema, "`bio` TEXT,")
+		assertContains(t, schema, "`nick` VARCHAR(64) CHARACTER SET utf8mb4 NOT NULL DEFAULT 'anon',")
+		assertContains(t, schema, "`price` DECIMAL(12,2) NOT NULL DEFAULT 0,")
+		assertContains(t, schema, "`age` INT UNSIGNED NOT NULL DEFAULT 0 COMMENT 'age, in years',")
+		assertContains(t, schema, "`views` BIGINT NOT NULL DEFAULT 0,")
+		assertContains(t, schema, "`counter` BIGINT UNSIGNED NOT NULL DEFAULT 0")
+	})
+
+	ts.Run("Indexes", func(t *testing.T) {
+		schema := mw.GenerateSchema(&UserPost{})
+		assertContains(t, schema, "KEY `idx_user_created` (`user_id`, `created`),")
+		assertContains(t, schema, "UNIQUE KEY `uniq_slug` (`slug`),")
+		assertContains(t, schema, "KEY `idx_title` (`title`),")
+		assertContains(t, schema, "KEY `idx_title_body` (`title`, `body`(100)),")
+		assertContains(t, schema, "CONSTRAINT `fk_user_post_user_id` FOREIGN KEY (`user_id`) REFERENCES `user` (`id`) ON DELETE CASCADE\n);")
+	})
+
+	ts.Run("Diff program", func(t *testing.T) {
+		program := mw.GenerateDiff("github.com/you/app/models", "User", "Post")
+		assertContains(t, program, `models "github.com/you/app/models"`)
+		assertContains(t, program, "mw.DiffSchema(mw.New(con), &models.User{}, &models.Post{})")
+	})
 }
@@ -0,0 +1,133 @@
+package mwear
+
+import (
+	"context"
+	"log"
+	"strings"
+	"time"
+)
+
+// QueryEvent describes a query executed by adapter.
+type QueryEvent struct {
+	// Op is the operation, like insert or select.
+	Op string
+	// Table is the name of the model table, empty for queries not bound to a model.
+	Table string
+	SQL   string
+	Args  []interface{}
+
+	// StartTime is the time query started, Duration is set after query is finished.
+	StartTime time.Time
+	Duration  time.Duration
+	// RowsAffected is the number of affected rows for insert, update and delete,
+	// and the number of scanned rows for select.
+	RowsAffected int64
+	// Err is the query error, set after query is finished.
+	Err error
+}
+
+// QueryHook is called around each query executed by adapter,
+// it may be used for logging, tracing or collecting metrics.
+type QueryHook interface {
+	// BeforeQuery is called before query is executed, returned context is passed
+	// to the query and to AfterQuery, so it may carry tracing span.
+	BeforeQuery(ctx context.Context, event *QueryEvent) context.Context
+	// AfterQuery is called after query is finished, for select queries after all rows are scanned.
+	AfterQuery(ctx context.Context, event *QueryEvent)
+}
+
+// AddQueryHook registers query hook, hooks are called in the order they are added.
+// Adapters created by RunInTx inherit hooks of the parent adapter.
+func (a *Adapter) AddQueryHook(hook QueryHook) {
+	// copy hooks, so adapters sharing them aren't affected.
+	a.hooks = append(a.hooks[:len(a.hooks):len(a.hooks)], hook)
+}
+
+func (a *Adapter) beforeQuery(ctx context.Context, op, table, query string, args []interface{}) (context.Context, *QueryEvent) {
+	if len(a.hooks) == 0 {
+		return ctx, nil
+	}
+
+	event := &QueryEvent{
+		Op:        op,
+		Table:     table,
+		SQL:       query,
+		Args:      args,
+		StartTime: time.Now(),
+	}
+	for _, hook := range a.hooks {
+		ctx = hook.BeforeQuery(ctx, event)
+	}
+
+	return ctx, event
+}
+
+func (a *Adapter) afterQuery(ctx context.Context, event *QueryEvent, rowsAffected int64, err error) {
+	if event == nil {
+		return
+	}
+
+	event.Duration = time.Since(event.StartTime)
+	event.RowsAffected = rowsAffected
+	event.Err = err
+	for _, hook := range a.hooks {
+		hook.AfterQuery(ctx, event)
+	}
+}
+
+// LogHook logs each query using standard logger.
+type LogHook struct {
+	logger *log.Logger
+}
+
+// NewLogHook creates hook logging queries with logger, in case logger is nil
+// queries are logged to the output of standard logger.
+func NewLogHook(logger *log.Logger) *LogHook {
+	if logger == nil {
+		logger = log.New(log.Writer(), log.Prefix(), log.Flags())
+	}
+	return &LogHook{logger: logger}
+}
+
+// BeforeQuery implements QueryHook.
+func (h *LogHook) BeforeQuery(ctx context.Context, event *QueryEvent) context.Context {
+	return ctx
+}
+
+// AfterQuery implements QueryHook.
+func (h *LogHook) AfterQuery(ctx context.Context, event *QueryEvent) {
+	if event.Err != nil {
+		h.logger.Printf("%s %s (%s) failed: %v\n%s %v", event.Op, event.Table, event.Duration, event.Err, strings.TrimSpace(event.SQL), event.Args)
+		return
+	}
+	h.logger.Printf("%s %s (%s), rows: %d\n%s %v", event.Op, event.Table, event.Duration, event.RowsAffected, strings.TrimSpace(event.SQL), event.Args)
+}
+
+// SlowQueryHook reports queries which take longer than threshold.
+type SlowQueryHook struct {
+	threshold time.Duration
+	report    func(ctx context.Context, event *QueryEvent)
+}
+
+// NewSlowQueryHook creates hook calling report for each query which takes longer than threshold.
+// In case report is nil, slow queries are logged using standard logger.
+func NewSlowQueryHook(threshold time.Duration, report func(ctx context.Context, event *QueryEvent)) *SlowQueryHook {
+	if report == nil {
+		report = func(ctx context.Context, event *QueryEvent) {
+			log.Printf("slow query: %s %s (%s)\n%s", event.Op, event.Table, event.Duration, strings.TrimSpace(event.SQL))
+		}
+	}
+	return &SlowQueryHook{threshold: threshold, report: report}
+}
+
+// BeforeQuery implements QueryHook.
+func (h *SlowQueryHook) BeforeQuery(ctx context.Context, event *QueryEvent) context.Context {
+	return ctx
+}
+
+// AfterQuery implements QueryHook.
+func (h *SlowQueryHook) AfterQuery(ctx context.Context, event *QueryEvent) {
+	if event.Duration >= h.threshold {
+		h.report(ctx, event)
+	}
+}
@@ -0,0 +1,56 @@
+//go:build go1.21
+// +build go1.21
+
+package mwear
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+)
+
+// SlogHook logs each query using structured logger.
+// Successful queries are logged with configured level, failed ones with error level.
+type SlogHook struct {
+	logger  *slog.Logger
+	level   slog.Level
+	logArgs bool
+}
+
+// NewSlogHook creates hook logging successful queries with level, slog.Default() is used in case logger is nil.
+// Query args are logged only in case logArgs is set, as they may contain sensitive data.
+func NewSlogHook(logger *slog.Logger, level slog.Level, logArgs bool) *SlogHook {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &SlogHook{logger: logger, level: level, logArgs: logArgs}
+}
+
+// BeforeQuery implements QueryHook.
+func (h *SlogHook) BeforeQuery(ctx context.Context, event *QueryEvent) context.Context {
+	return ctx
+}
+
+// AfterQuery implements QueryHook.
+func (h *SlogHook) AfterQuery(ctx context.Context, event *QueryEvent) {
+	level := h.level
+	attrs := make([]slog.Attr, 0, 7)
+	attrs = append(attrs,
+		slog.String("op", event.Op),
+		slog.String("table", event.Table),
+		slog.String("sql", strings.TrimSpace(event.SQL)),
+		slog.Duration("duration", event.Duration),
+		slog.Int64("rows", event.RowsAffected),
+	)
+	if h.logArgs {
+		attrs = append(attrs, slog.Any("args", event.Args))
+	}
+	if event.Err != nil {
+		level = slog.LevelError
+		attrs = append(attrs, slog.Any("error", event.Err))
+	}
+	if !h.logger.Enabled(ctx, level) {
+		return
+	}
+	h.logger.LogAttrs(ctx, level, "mw query", attrs...)
+}
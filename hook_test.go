@@ -0,0 +1,122 @@
+package mwear
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cliqueinc/mysql-wear/sqlq"
+)
+
+type ctxKey string
+
+type recordHook struct {
+	mx     sync.Mutex
+	events []QueryEvent
+}
+
+func (h *recordHook) BeforeQuery(ctx context.Context, event *QueryEvent) context.Context {
+	return context.WithValue(ctx, ctxKey("op"), event.Op)
+}
+
+func (h *recordHook) AfterQuery(ctx context.Context, event *QueryEvent) {
+	if ctx.Value(ctxKey("op")) != event.Op {
+		panic("context returned by BeforeQuery expected to be passed to AfterQuery")
+	}
+	h.mx.Lock()
+	h.events = append(h.events, *event)
+	h.mx.Unlock()
+}
+
+func (h *recordHook) last() QueryEvent {
+	h.mx.Lock()
+	defer h.mx.Unlock()
+	return h.events[len(h.events)-1]
+}
+
+func TestQueryHook(t *testing.T) {
+	type hookUser struct {
+		ID   int64
+		Name string
+	}
+
+	hook := &recordHook{}
+	hookDB := New(db.DB)
+	hookDB.AddQueryHook(hook)
+
+	if err := hookDB.CreateTable(&hookUser{}); err != nil {
+		t.Fatalf("fail create table: %v", err)
+	}
+	if e := hook.last(); e.Op != OpCreateTable || e.Table != "hook_user" {
+		t.Errorf("unexpected create table event (%+v)", e)
+	}
+
+	hookDB.MustInsert(&hookUser{Name: "first"}, &hookUser{Name: "second"})
+	e := hook.last()
+	if e.Op != OpInsert || e.Table != "hook_user" || e.RowsAffected != 2 || len(e.Args) != 4 || e.Err != nil {
+		t.Errorf("unexpected insert event (%+v)", e)
+	}
+	if e.SQL == "" || e.StartTime.IsZero() || e.Duration <= 0 {
+		t.Errorf("query sql and time expected to be set (%+v)", e)
+	}
+
+	var users []hookUser
+	hookDB.MustSelect(&users, sqlq.Order("id", sqlq.ASC))
+	if e := hook.last(); e.Op != OpSelect || e.RowsAffected != 2 {
+		t.Errorf("unexpected select event (%+v)", e)
+	}
+
+	if found := hookDB.MustGet(&hookUser{ID: users[0].ID}); !found {
+		t.Fatalf("user not found")
+	}
+	if e := hook.last(); e.Op != OpGet || e.RowsAffected != 1 {
+		t.Errorf("unexpected get event (%+v)", e)
+	}
+
+	err := hookDB.ForEach(&hookUser{}, func() error { return nil })
+	if err != nil {
+		t.Fatalf("fail iterate: %v", err)
+	}
+	if e := hook.last(); e.Op != OpSelect || e.RowsAffected != 2 {
+		t.Errorf("unexpected iterate event (%+v)", e)
+	}
+
+	_, err = hookDB.Insert(&hookUser{ID: users[0].ID})
+	if e := hook.last(); e.Op != OpInsert || e.Err == nil || !errors.Is(e.Err, ErrUniqueViolation) || e.Err != err {
+		t.Errorf("unexpected failed insert event (%+v)", e)
+	}
+
+	t.Run("transaction", func(t *testing.T) {
+		err := hookDB.RunInTx(context.Background(), nil, func(a *Adapter) error {
+			return a.Update(&hookUser{ID: users[0].ID, Name: "updated"})
+		})
+		if err != nil {
+			t.Fatalf("fail update: %v", err)
+		}
+		if e := hook.last(); e.Op != OpUpdate || e.RowsAffected != 1 {
+			t.Errorf("transaction adapter expected to inherit hooks, last event (%+v)", e)
+		}
+	})
+
+	t.Run("slow query", func(t *testing.T) {
+		var slow []*QueryEvent
+		report := func(ctx context.Context, event *QueryEvent) {
+			slow = append(slow, event)
+		}
+		slowDB := New(db.DB)
+		slowDB.AddQueryHook(NewSlowQueryHook(time.Hour, report))
+		slowDB.MustCount(&hookUser{})
+		if len(slow) != 0 {
+			t.Errorf("fast query reported as slow")
+		}
+
+		slowDB = New(db.DB)
+		slowDB.AddQueryHook(NewSlowQueryHook(time.Nanosecond, report))
+		slowDB.MustCount(&hookUser{})
+		if len(slow) != 1 || slow[0].Op != OpCount {
+			t.Errorf("slow query expected to be reported, got (%+v)", slow)
+		}
+	})
+}
@@ -0,0 +1,238 @@
+package mwear
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Index describes table index generated by GenerateSchema.
+type Index struct {
+	Name string
+	// Columns are indexed column names, column may have prefix length or order, e.g. "name(10)" or "created DESC".
+	Columns []string
+	Unique  bool
+}
+
+// Indexer may be implemented by a model to declare indexes, which cannot be declared with index and unique tags:
+//
+//	func (u *User) Indexes() []mw.Index {
+//		return []mw.Index{
+//			{Name: "idx_user_name", Columns: []string{"last_name(10)", "first_name(10)"}},
+//		}
+//	}
+type Indexer interface {
+	Indexes() []Index
+}
+
+// Definition returns index definition used in create table statement.
+func (idx Index) Definition() string {
+	cols := make([]string, 0, len(idx.Columns))
+	for _, col := range idx.Columns {
+		cols = append(cols, quoteIndexColumn(col))
+	}
+	def := "KEY `" + idx.Name + "` (" + strings.Join(cols, ", ") + ")"
+	if idx.Unique {
+		def = "UNIQUE " + def
+	}
+	return def
+}
+
+// foreignKey describes foreign key declared with fk tag.
+type foreignKey struct {
+	Name      string
+	Column    string
+	RefTable  string
+	RefColumn string
+	OnDelete  string
+	OnUpdate  string
+}
+
+// Definition returns foreign key definition used in create table statement.
+func (fk foreignKey) Definition() string {
+	def := fmt.Sprintf("CONSTRAINT `%s` FOREIGN KEY (`%s`) REFERENCES `%s` (`%s`)", fk.Name, fk.Column, fk.RefTable, fk.RefColumn)
+	if fk.OnDelete != "" {
+		def += " ON DELETE " + fk.OnDelete
+	}
+	if fk.OnUpdate != "" {
+		def += " ON UPDATE " + fk.OnUpdate
+	}
+	return def
+}
+
+// indexPart is a column of an index declared with field tag.
+type indexPart struct {
+	name   string
+	unique bool
+	column string
+	order  int
+}
+
+// isIndexOption checks whether mw tag option declares index or foreign key, e.g. "index:idx_name".
+func isIndexOption(opt string) bool {
+	name := opt
+	if ind := strings.Index(opt, ":"); ind != -1 {
+		name = opt[:ind]
+	}
+	switch strings.TrimSpace(name) {
+	case "index", "unique", "order", "fk", "on_delete", "on_update":
+		return true
+	}
+	return false
+}
+
+// setFieldIndexes adds indexes and foreign key declared with field tag options.
+func (mod *model) setFieldIndexes(fi *field, tagOpts []string) error {
+	var (
+		parts []indexPart
+		order int
+		fk    *foreignKey
+	)
+	for _, opt := range tagOpts {
+		opt = strings.TrimSpace(opt)
+		if !isIndexOption(opt) {
+			continue
+		}
+		name, val := opt, ""
+		if ind := strings.Index(opt, ":"); ind != -1 {
+			name, val = strings.TrimSpace(opt[:ind]), strings.TrimSpace(opt[ind+1:])
+		}
+
+		switch name {
+		case "index", "unique":
+			if val == "" {
+				prefix := "idx_"
+				if name == "unique" {
+					prefix = "uniq_"
+				}
+				val = prefix + fi.MWName
+			}
+			parts = append(parts, indexPart{name: val, unique: name == "unique", column: fi.MWName})
+		case "order":
+			num, err := strconv.Atoi(val)
+			if err != nil || num <= 0 {
+				return fmt.Errorf("invalid index order (%s)", val)
+			}
+			order = num
+		case "fk":
+			dot := strings.Index(val, ".")
+			if dot <= 0 || dot == len(val)-1 {
+				return fmt.Errorf("invalid foreign key reference (%s), table.column expected", val)
+			}
+			fk = &foreignKey{
+				Name:      "fk_" + mod.TableName + "_" + fi.MWName,
+				Column:    fi.MWName,
+				RefTable:  val[:dot],
+				RefColumn: val[dot+1:],
+			}
+		case "on_delete", "on_update":
+			if fk == nil {
+				return fmt.Errorf("%s option requires fk option before it", name)
+			}
+			action, err := referenceAction(val)
+			if err != nil {
+				return err
+			}
+			if name == "on_delete" {
+				fk.OnDelete = action
+			} else {
+				fk.OnUpdate = action
+			}
+		}
+	}
+	if order != 0 && len(parts) == 0 {
+		return errors.New("order option requires index or unique option")
+	}
+	for _, part := range parts {
+		part.order = order
+		mod.indexParts = append(mod.indexParts, part)
+	}
+	if fk != nil {
+		mod.ForeignKeys = append(mod.ForeignKeys, *fk)
+	}
+	return nil
+}
+
+// buildIndexes combines index parts declared with field tags into indexes, columns are sorted by order option,
+// then indexes declared by Indexes method are added.
+func (mod *model) buildIndexes(structPtr interface{}) error {
+	byName := make(map[string]int)
+	for i := range mod.indexParts {
+		part := mod.indexParts[i]
+		ind, ok := byName[part.name]
+		if !ok {
+			byName[part.name] = len(mod.Indexes)
+			mod.Indexes = append(mod.Indexes, Index{Name: part.name, Unique: part.unique})
+			continue
+		}
+		if mod.Indexes[ind].Unique != part.unique {
+			return fmt.Errorf("index (%s) is declared both unique and not unique", part.name)
+		}
+	}
+	for i := range mod.Indexes {
+		var parts []indexPart
+		for _, part := range mod.indexParts {
+			if part.name == mod.Indexes[i].Name {
+				parts = append(parts, part)
+			}
+		}
+		// columns without order keep their field order after ordered ones.
+		sort.SliceStable(parts, func(a, b int) bool {
+			if parts[a].order == 0 || parts[b].order == 0 {
+				return parts[a].order != 0 && parts[b].order == 0
+			}
+			return parts[a].order < parts[b].order
+		})
+		for _, part := range parts {
+			mod.Indexes[i].Columns = append(mod.Indexes[i].Columns, part.column)
+		}
+	}
+	mod.indexParts = nil
+
+	indexer, ok := structPtr.(Indexer)
+	if !ok {
+		return nil
+	}
+	for _, idx := range indexer.Indexes() {
+		if idx.Name == "" || len(idx.Columns) == 0 {
+			return errors.New("index name and columns cannot be empty")
+		}
+		if _, ok := byName[idx.Name]; ok {
+			return fmt.Errorf("duplicate index (%s)", idx.Name)
+		}
+		for _, col := range idx.Columns {
+			if _, err := mod.getFields([]string{indexColumnName(col)}); err != nil {
+				return fmt.Errorf("index (%s): %v", idx.Name, err)
+			}
+		}
+		byName[idx.Name] = len(mod.Indexes)
+		mod.Indexes = append(mod.Indexes, idx)
+	}
+	return nil
+}
+
+// referenceAction returns foreign key reference action by tag value, e.g. "set_null" is SET NULL.
+func referenceAction(val string) (string, error) {
+	action := strings.ToUpper(strings.Replace(val, "_", " ", -1))
+	switch action {
+	case "CASCADE", "SET NULL", "RESTRICT", "NO ACTION":
+		return action, nil
+	}
+	return "", fmt.Errorf("invalid foreign key action (%s)", val)
+}
+
+// indexColumnName returns column name of index column, which may have prefix length or order.
+func indexColumnName(col string) string {
+	if ind := strings.IndexAny(col, "( "); ind != -1 {
+		return col[:ind]
+	}
+	return col
+}
+
+// quoteIndexColumn quotes column name of index column, keeping prefix length or order.
+func quoteIndexColumn(col string) string {
+	name := indexColumnName(col)
+	return "`" + name + "`" + col[len(name):]
+}
@@ -0,0 +1,185 @@
+package mwear
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"reflect"
+
+	"github.com/cliqueinc/mysql-wear/sqlq"
+)
+
+// Iterator iterates over select result row by row, without loading all rows into memory.
+//
+//	it, err := db.Iterate(&user{}, sqlq.Order("id", sqlq.ASC))
+//	if err != nil {
+//		return err
+//	}
+//	defer it.Close()
+//	for it.Next() {
+//		u := &user{}
+//		if err := it.Scan(u); err != nil {
+//			return err
+//		}
+//	}
+//	return it.Err()
+type Iterator struct {
+	rows    *sql.Rows
+	scanner *rowScanner
+	sql     string
+
+	// adapter, ctx and event are used to call query hooks once iterator is closed.
+	adapter *Adapter
+	ctx     context.Context
+	event   *QueryEvent
+	scanned int64
+
+	// current is the model returned by Scan, next is the model
+	// being scanned, which may still receive joined rows.
+	current reflect.Value
+	next    reflect.Value
+	err     error
+}
+
+// Iterate performs select using query options and returns iterator over result rows.
+// Unlike Select, no default limit is added to the query.
+// In case of one to many joins, rows of the same model are expected to follow each other
+// (e.g. ordered by primary key), then they are merged into one model.
+// Iterator should be closed after use.
+func (a *Adapter) Iterate(structPtr interface{}, opts ...sqlq.Option) (*Iterator, error) {
+	return a.IterateContext(context.Background(), structPtr, opts...)
+}
+
+// IterateContext is the same as Iterate, but allows to cancel query using context.
+func (a *Adapter) IterateContext(ctx context.Context, structPtr interface{}, opts ...sqlq.Option) (*Iterator, error) {
+	// sqlq.All disables default select limit.
+	iterOpts := make([]sqlq.Option, 0, len(opts)+1)
+	iterOpts = append(append(iterOpts, opts...), sqlq.All())
+	mod, err := parseModel(structPtr, true)
+	if err != nil {
+		return nil, err
+	}
+	stmt, err := sqlq.Build(mod.withSoftDelete(iterOpts), sqlq.OpSelect)
+	if err != nil {
+		return nil, err
+	}
+	finalSQL, joinMods, joinFields, err := selectSQL(mod, stmt)
+	if err != nil {
+		return nil, err
+	}
+
+	scanner, err := newRowScanner(mod, stmt.Columns, joinMods, joinFields)
+	if err != nil {
+		return nil, err
+	}
+	ctx, event := a.beforeQuery(ctx, OpSelect, mod.TableName, finalSQL, stmt.Args)
+	rows, err := a.query(ctx, OpSelect, mod.TableName, finalSQL, stmt.Args...)
+	if err != nil {
+		a.afterQuery(ctx, event, 0, err)
+		return nil, err
+	}
+
+	return &Iterator{
+		rows:    rows,
+		scanner: scanner,
+		sql:     finalSQL,
+		adapter: a,
+		ctx:     ctx,
+		event:   event,
+	}, nil
+}
+
+// Next prepares the next model for reading with Scan. Returns false if there are
+// no more rows or an error occurred, in that case iterator is closed.
+func (it *Iterator) Next() bool {
+	if it.err != nil || it.rows == nil {
+		return false
+	}
+
+	for it.rows.Next() {
+		rowModel, isNew, err := it.scanner.scan(it.rows)
+		if err != nil {
+			it.err = newQueryError(OpSelect, it.scanner.mod.TableName, it.sql, fmt.Errorf("scan error: %w", err))
+			it.Close()
+			return false
+		}
+		it.scanned++
+		// new model scanned, so the previous one won't receive any joined row anymore.
+		if isNew && it.next.IsValid() {
+			it.current = it.next
+			it.next = rowModel
+			return true
+		}
+		it.next = rowModel
+	}
+	if err := it.rows.Err(); err != nil {
+		it.err = newQueryError(OpSelect, it.scanner.mod.TableName, it.sql, err)
+	}
+
+	if it.err == nil && it.next.IsValid() {
+		it.current = it.next
+		it.next = reflect.Value{}
+		return true
+	}
+	it.Close()
+	return false
+}
+
+// Scan copies current model into structPtr, which should be a pointer to the iterated struct type.
+func (it *Iterator) Scan(structPtr interface{}) error {
+	if !it.current.IsValid() {
+		return errors.New("scan called without calling Next")
+	}
+	rv := reflect.ValueOf(structPtr)
+	if rv.Type() != it.current.Type() || rv.IsNil() {
+		return fmt.Errorf("cannot scan into (%T), pointer to (%s) expected", structPtr, it.current.Type().Elem())
+	}
+	rv.Elem().Set(it.current.Elem())
+	it.scanner.mod.takeSnapshot(rv)
+
+	return afterLoad(it.ctx, structPtr)
+}
+
+// Err returns an error occurred during iteration.
+func (it *Iterator) Err() error {
+	return it.err
+}
+
+// Close closes iterator, it is safe to call Close multiple times.
+func (it *Iterator) Close() error {
+	if it.rows == nil {
+		return nil
+	}
+	err := it.rows.Close()
+	it.rows = nil
+	it.adapter.afterQuery(it.ctx, it.event, it.scanned, it.err)
+
+	return err
+}
+
+// ForEach iterates over select result, fn is called for each row after it is scanned into structPtr.
+// Iteration stops in case fn returns an error, which is returned by ForEach.
+func (a *Adapter) ForEach(structPtr interface{}, fn func() error, opts ...sqlq.Option) error {
+	return a.ForEachContext(context.Background(), structPtr, fn, opts...)
+}
+
+// ForEachContext is the same as ForEach, but allows to cancel query using context.
+func (a *Adapter) ForEachContext(ctx context.Context, structPtr interface{}, fn func() error, opts ...sqlq.Option) error {
+	it, err := a.IterateContext(ctx, structPtr, opts...)
+	if err != nil {
+		return err
+	}
+	defer it.Close()
+
+	for it.Next() {
+		if err := it.Scan(structPtr); err != nil {
+			return err
+		}
+		if err := fn(); err != nil {
+			return err
+		}
+	}
+
+	return it.Err()
+}
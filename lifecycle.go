@@ -0,0 +1,87 @@
+package mwear
+
+import (
+	"context"
+	"reflect"
+)
+
+// Model lifecycle hooks, models implementing them (with pointer receiver) are called by adapter
+// around Insert, Update, Delete and after models are loaded by Get, Select or Iterate.
+// An error returned from a before hook aborts the operation, an error from an after hook
+// is returned by the operation, though the query is already executed.
+// Operations on multiple rows by query (UpdateRows, DeleteRows) and Upsert don't call hooks.
+type (
+	// BeforeInserter is called before model is inserted.
+	BeforeInserter interface {
+		BeforeInsert(ctx context.Context) error
+	}
+	// AfterInserter is called after model is inserted, auto increment id is already set.
+	AfterInserter interface {
+		AfterInsert(ctx context.Context) error
+	}
+	// BeforeUpdater is called before model is updated.
+	BeforeUpdater interface {
+		BeforeUpdate(ctx context.Context) error
+	}
+	// AfterUpdater is called after model is updated.
+	AfterUpdater interface {
+		AfterUpdate(ctx context.Context) error
+	}
+	// BeforeDeleter is called before model is deleted.
+	BeforeDeleter interface {
+		BeforeDelete(ctx context.Context) error
+	}
+	// AfterDeleter is called after model is deleted.
+	AfterDeleter interface {
+		AfterDelete(ctx context.Context) error
+	}
+	// AfterLoader is called after model is loaded from db.
+	AfterLoader interface {
+		AfterLoad(ctx context.Context) error
+	}
+)
+
+func beforeInsert(ctx context.Context, structPtrs []interface{}) error {
+	for _, structPtr := range structPtrs {
+		if h, ok := structPtr.(BeforeInserter); ok {
+			if err := h.BeforeInsert(ctx); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func afterInsert(ctx context.Context, structPtrs []interface{}) error {
+	for _, structPtr := range structPtrs {
+		if h, ok := structPtr.(AfterInserter); ok {
+			if err := h.AfterInsert(ctx); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// afterLoadSlice calls AfterLoad for each model of the slice, starting from position start.
+func afterLoadSlice(ctx context.Context, sliceVal reflect.Value, start int) error {
+	if sliceVal.Len() <= start {
+		return nil
+	}
+	if _, ok := sliceVal.Index(start).Addr().Interface().(AfterLoader); !ok {
+		return nil
+	}
+	for i := start; i < sliceVal.Len(); i++ {
+		if err := sliceVal.Index(i).Addr().Interface().(AfterLoader).AfterLoad(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func afterLoad(ctx context.Context, structPtr interface{}) error {
+	if h, ok := structPtr.(AfterLoader); ok {
+		return h.AfterLoad(ctx)
+	}
+	return nil
+}
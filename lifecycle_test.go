@@ -0,0 +1,140 @@
+package mwear
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/cliqueinc/mysql-wear/sqlq"
+)
+
+var errLifecycleInvalid = errors.New("invalid name")
+
+type lifecycleUser struct {
+	ID      int64
+	Name    string
+	Created time.Time
+	Updated time.Time
+
+	calls []string `mw:"-"`
+}
+
+func (u *lifecycleUser) BeforeInsert(ctx context.Context) error {
+	if u.Name == "" {
+		return errLifecycleInvalid
+	}
+	u.Created = time.Now().UTC().Truncate(time.Second)
+	u.Updated = u.Created
+	u.calls = append(u.calls, "before_insert")
+	return nil
+}
+
+func (u *lifecycleUser) AfterInsert(ctx context.Context) error {
+	u.calls = append(u.calls, "after_insert")
+	return nil
+}
+
+func (u *lifecycleUser) BeforeUpdate(ctx context.Context) error {
+	u.Updated = time.Now().UTC().Add(time.Hour).Truncate(time.Second)
+	u.calls = append(u.calls, "before_update")
+	return nil
+}
+
+func (u *lifecycleUser) AfterUpdate(ctx context.Context) error {
+	u.calls = append(u.calls, "after_update")
+	return nil
+}
+
+func (u *lifecycleUser) BeforeDelete(ctx context.Context) error {
+	if u.Name == "protected" {
+		return errLifecycleInvalid
+	}
+	u.calls = append(u.calls, "before_delete")
+	return nil
+}
+
+func (u *lifecycleUser) AfterDelete(ctx context.Context) error {
+	u.calls = append(u.calls, "after_delete")
+	return nil
+}
+
+func (u *lifecycleUser) AfterLoad(ctx context.Context) error {
+	u.calls = append(u.calls, "after_load")
+	return nil
+}
+
+func assertCalls(t *testing.T, u *lifecycleUser, expected ...string) {
+	t.Helper()
+	if len(u.calls) != len(expected) {
+		t.Fatalf("expected hook calls (%v), actual (%v)", expected, u.calls)
+	}
+	for i := range expected {
+		if u.calls[i] != expected[i] {
+			t.Fatalf("expected hook calls (%v), actual (%v)", expected, u.calls)
+		}
+	}
+}
+
+func TestLifecycleHooks(t *testing.T) {
+	db.MustCreateTable(&lifecycleUser{})
+
+	u := &lifecycleUser{Name: "first"}
+	db.MustInsert(u)
+	assertCalls(t, u, "before_insert", "after_insert")
+	if u.ID == 0 || u.Created.IsZero() {
+		t.Fatalf("insert hooks expected to run with id and created set (%+v)", u)
+	}
+
+	_, err := db.Insert(&lifecycleUser{Name: "second"}, &lifecycleUser{})
+	if err != errLifecycleInvalid {
+		t.Fatalf("expected before insert error, got (%v)", err)
+	}
+	if cnt := db.MustCount(&lifecycleUser{}); cnt != 1 {
+		t.Fatalf("failed before insert hook expected to abort insert, found (%d) rows", cnt)
+	}
+
+	u.calls = nil
+	u.Name = "updated"
+	db.MustUpdate(u)
+	assertCalls(t, u, "before_update", "after_update")
+
+	loaded := &lifecycleUser{ID: u.ID}
+	if found := db.MustGet(loaded); !found {
+		t.Fatalf("user not found")
+	}
+	assertCalls(t, loaded, "after_load")
+	if !loaded.Updated.Equal(u.Updated) || loaded.Name != "updated" {
+		t.Errorf("expected updated user (%+v), actual (%+v)", u, loaded)
+	}
+
+	db.MustInsert(&lifecycleUser{Name: "protected"})
+	var users []lifecycleUser
+	db.MustSelect(&users, sqlq.Order("id", sqlq.ASC))
+	if len(users) != 2 {
+		t.Fatalf("expected 2 users, actual (%d)", len(users))
+	}
+	for i := range users {
+		assertCalls(t, &users[i], "after_load")
+	}
+
+	it := &lifecycleUser{}
+	err = db.ForEach(it, func() error {
+		assertCalls(t, it, "after_load")
+		it.calls = nil
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("fail iterate: %v", err)
+	}
+
+	if err := db.Delete(&users[1]); err != errLifecycleInvalid {
+		t.Fatalf("expected before delete error, got (%v)", err)
+	}
+	users[0].calls = nil
+	db.MustDelete(&users[0])
+	assertCalls(t, &users[0], "before_delete", "after_delete")
+	if cnt := db.MustCount(&lifecycleUser{}); cnt != 1 {
+		t.Fatalf("expected 1 user left, found (%d)", cnt)
+	}
+}
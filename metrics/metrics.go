@@ -0,0 +1,215 @@
+// Package metrics collects query metrics of mysql-wear adapter: number of queries and errors,
+// latency histograms and number of affected or scanned rows, labelled by operation and table.
+//
+//	collector := metrics.NewCollector()
+//	db.AddQueryHook(metrics.NewHook(collector))
+//	http.Handle("/metrics", collector)
+//
+// Recorder interface may be implemented to report metrics to other systems, like prometheus client.
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	mw "github.com/cliqueinc/mysql-wear"
+)
+
+// DefaultBuckets are the upper bounds of latency histogram buckets in seconds.
+var DefaultBuckets = []float64{.001, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// Recorder records measurements of executed queries.
+type Recorder interface {
+	// ObserveQuery is called after each query, rows is the number of affected rows
+	// for insert, update and delete and the number of scanned rows for select.
+	ObserveQuery(op, table string, duration time.Duration, rows int64, err error)
+}
+
+// Hook is a query hook passing query measurements to recorder.
+type Hook struct {
+	recorder Recorder
+}
+
+var _ mw.QueryHook = (*Hook)(nil)
+
+// NewHook creates query hook reporting to recorder.
+func NewHook(recorder Recorder) *Hook {
+	return &Hook{recorder: recorder}
+}
+
+// BeforeQuery implements mw.QueryHook.
+func (h *Hook) BeforeQuery(ctx context.Context, event *mw.QueryEvent) context.Context {
+	return ctx
+}
+
+// AfterQuery implements mw.QueryHook.
+func (h *Hook) AfterQuery(ctx context.Context, event *mw.QueryEvent) {
+	h.recorder.ObserveQuery(event.Op, event.Table, event.Duration, event.RowsAffected, event.Err)
+}
+
+// Labels identify metrics of particular operation on a table.
+type Labels struct {
+	Op    string
+	Table string
+}
+
+// Stats keeps metrics of queries with the same labels.
+type Stats struct {
+	Queries int64
+	Errors  int64
+	Rows    int64
+	// Duration is the total duration of queries.
+	Duration time.Duration
+	// Buckets holds the number of queries per latency bucket, not cumulative,
+	// the last element counts queries exceeding the last bucket bound.
+	Buckets []int64
+}
+
+// Collector keeps query metrics in memory, and exposes them in prometheus text format.
+type Collector struct {
+	buckets []float64
+
+	mx    sync.Mutex
+	stats map[Labels]*Stats
+}
+
+var _ Recorder = (*Collector)(nil)
+
+// NewCollector creates collector with latency buckets in seconds, DefaultBuckets are used if none passed.
+func NewCollector(buckets ...float64) *Collector {
+	if len(buckets) == 0 {
+		buckets = DefaultBuckets
+	}
+	sorted := make([]float64, len(buckets))
+	copy(sorted, buckets)
+	sort.Float64s(sorted)
+
+	return &Collector{
+		buckets: sorted,
+		stats:   make(map[Labels]*Stats),
+	}
+}
+
+// ObserveQuery implements Recorder.
+func (c *Collector) ObserveQuery(op, table string, duration time.Duration, rows int64, err error) {
+	labels := Labels{Op: op, Table: table}
+	bucket := sort.SearchFloat64s(c.buckets, duration.Seconds())
+
+	c.mx.Lock()
+	defer c.mx.Unlock()
+
+	st, ok := c.stats[labels]
+	if !ok {
+		st = &Stats{Buckets: make([]int64, len(c.buckets)+1)}
+		c.stats[labels] = st
+	}
+	st.Queries++
+	if err != nil {
+		st.Errors++
+	}
+	st.Rows += rows
+	st.Duration += duration
+	st.Buckets[bucket]++
+}
+
+// Stats returns a copy of metrics of queries with specified operation and table.
+func (c *Collector) Stats(op, table string) Stats {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+
+	st, ok := c.stats[Labels{Op: op, Table: table}]
+	if !ok {
+		return Stats{Buckets: make([]int64, len(c.buckets)+1)}
+	}
+	res := *st
+	res.Buckets = append([]int64(nil), st.Buckets...)
+
+	return res
+}
+
+// Reset removes all collected metrics.
+func (c *Collector) Reset() {
+	c.mx.Lock()
+	c.stats = make(map[Labels]*Stats)
+	c.mx.Unlock()
+}
+
+// snapshot returns copy of metrics sorted by labels.
+func (c *Collector) snapshot() ([]Labels, map[Labels]Stats) {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+
+	labels := make([]Labels, 0, len(c.stats))
+	stats := make(map[Labels]Stats, len(c.stats))
+	for l, st := range c.stats {
+		labels = append(labels, l)
+		res := *st
+		res.Buckets = append([]int64(nil), st.Buckets...)
+		stats[l] = res
+	}
+	sort.Slice(labels, func(i, j int) bool {
+		if labels[i].Op != labels[j].Op {
+			return labels[i].Op < labels[j].Op
+		}
+		return labels[i].Table < labels[j].Table
+	})
+
+	return labels, stats
+}
+
+// WriteTo writes metrics in prometheus text exposition format.
+func (c *Collector) WriteTo(w io.Writer) (int64, error) {
+	labels, stats := c.snapshot()
+	b := &strings.Builder{}
+
+	counters := []struct {
+		name, help string
+		value      func(st Stats) int64
+	}{
+		{"mw_queries_total", "Number of executed queries.", func(st Stats) int64 { return st.Queries }},
+		{"mw_query_errors_total", "Number of failed queries.", func(st Stats) int64 { return st.Errors }},
+		{"mw_query_rows_total", "Number of affected or scanned rows.", func(st Stats) int64 { return st.Rows }},
+	}
+	for _, counter := range counters {
+		fmt.Fprintf(b, "# HELP %s %s\n# TYPE %s counter\n", counter.name, counter.help, counter.name)
+		for _, l := range labels {
+			fmt.Fprintf(b, "%s{%s} %d\n", counter.name, formatLabels(l), counter.value(stats[l]))
+		}
+	}
+
+	const histogram = "mw_query_duration_seconds"
+	fmt.Fprintf(b, "# HELP %s Query duration in seconds.\n# TYPE %s histogram\n", histogram, histogram)
+	for _, l := range labels {
+		st := stats[l]
+		var cumulative int64
+		for i, bound := range c.buckets {
+			cumulative += st.Buckets[i]
+			fmt.Fprintf(b, "%s_bucket{%s,le=\"%s\"} %d\n", histogram, formatLabels(l), strconv.FormatFloat(bound, 'g', -1, 64), cumulative)
+		}
+		fmt.Fprintf(b, "%s_bucket{%s,le=\"+Inf\"} %d\n", histogram, formatLabels(l), st.Queries)
+		fmt.Fprintf(b, "%s_sum{%s} %s\n", histogram, formatLabels(l), strconv.FormatFloat(st.Duration.Seconds(), 'g', -1, 64))
+		fmt.Fprintf(b, "%s_count{%s} %d\n", histogram, formatLabels(l), st.Queries)
+	}
+
+	n, err := io.WriteString(w, b.String())
+	return int64(n), err
+}
+
+// ServeHTTP serves metrics in prometheus text exposition format.
+func (c *Collector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	c.WriteTo(w)
+}
+
+var labelReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func formatLabels(l Labels) string {
+	return fmt.Sprintf(`op="%s",table="%s"`, labelReplacer.Replace(l.Op), labelReplacer.Replace(l.Table))
+}
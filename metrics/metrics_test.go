@@ -0,0 +1,67 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	mw "github.com/cliqueinc/mysql-wear"
+)
+
+func TestCollector(t *testing.T) {
+	c := NewCollector(0.01, 0.1)
+	hook := NewHook(c)
+
+	events := []*mw.QueryEvent{
+		{Op: mw.OpInsert, Table: "user", Duration: 5 * time.Millisecond, RowsAffected: 2},
+		{Op: mw.OpInsert, Table: "user", Duration: 50 * time.Millisecond, Err: errors.New("duplicate")},
+		{Op: mw.OpSelect, Table: "user", Duration: time.Second, RowsAffected: 10},
+	}
+	for _, event := range events {
+		ctx := hook.BeforeQuery(context.Background(), event)
+		hook.AfterQuery(ctx, event)
+	}
+
+	st := c.Stats(mw.OpInsert, "user")
+	if st.Queries != 2 || st.Errors != 1 || st.Rows != 2 || st.Duration != 55*time.Millisecond {
+		t.Errorf("unexpected insert stats (%+v)", st)
+	}
+	if len(st.Buckets) != 3 || st.Buckets[0] != 1 || st.Buckets[1] != 1 || st.Buckets[2] != 0 {
+		t.Errorf("unexpected insert buckets (%v)", st.Buckets)
+	}
+	st = c.Stats(mw.OpSelect, "user")
+	if st.Queries != 1 || st.Rows != 10 || st.Buckets[2] != 1 {
+		t.Errorf("unexpected select stats (%+v)", st)
+	}
+	if st := c.Stats(mw.OpDelete, "user"); st.Queries != 0 {
+		t.Errorf("unexpected delete stats (%+v)", st)
+	}
+
+	b := &strings.Builder{}
+	if _, err := c.WriteTo(b); err != nil {
+		t.Fatalf("fail write metrics: %v", err)
+	}
+	out := b.String()
+	for _, line := range []string{
+		`mw_queries_total{op="insert",table="user"} 2`,
+		`mw_query_errors_total{op="insert",table="user"} 1`,
+		`mw_query_rows_total{op="select",table="user"} 10`,
+		`mw_query_duration_seconds_bucket{op="insert",table="user",le="0.01"} 1`,
+		`mw_query_duration_seconds_bucket{op="insert",table="user",le="0.1"} 2`,
+		`mw_query_duration_seconds_bucket{op="select",table="user",le="0.1"} 0`,
+		`mw_query_duration_seconds_bucket{op="select",table="user",le="+Inf"} 1`,
+		`mw_query_duration_seconds_sum{op="insert",table="user"} 0.055`,
+		`mw_query_duration_seconds_count{op="select",table="user"} 1`,
+	} {
+		if !strings.Contains(out, line+"\n") {
+			t.Errorf("metrics output doesn't contain (%s):\n%s", line, out)
+		}
+	}
+
+	c.Reset()
+	if st := c.Stats(mw.OpInsert, "user"); st.Queries != 0 {
+		t.Errorf("stats expected to be reset, got (%+v)", st)
+	}
+}
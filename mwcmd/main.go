@@ -4,6 +4,7 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"log"
 	"os"
 	"strconv"
 	"strings"
@@ -21,7 +22,11 @@ const (
 	DB_MIGRATION_PATH = "DB_MIGRATION_PATH"
 )
 
-var db *mw.DB
+var (
+	db *mw.DB
+	// debug enables query logging.
+	debug bool
+)
 
 /*
 How will be used:
@@ -33,6 +38,8 @@ How will be used:
 func help() {
 	fmt.Println(`Basic Commands: "$ mwcmd up|init|rollback|status"`)
 	fmt.Println(`Generate init: "$ mwcmd gen init StructName shortName"`)
+	fmt.Println(`Generate diff migration: "$ mwcmd gen diff github.com/you/app/models StructName..."`)
+	fmt.Println(`Generate model of existing table: "$ mwcmd gen model --table table_name [shortName]"`)
 	fmt.Println("")
 	fmt.Println("For commands other than gen, pass -d for debug query logging")
 	os.Exit(-1)
@@ -52,7 +59,7 @@ func main() {
 	if len(args) > 2 {
 		arg2 = args[2]
 		if arg2 == "-d" {
-			// GetConfig().LogQueries = true
+			debug = true
 			if len(args) > 3 && !strings.HasPrefix(args[3], "-") {
 				arg2 = args[3]
 			}
@@ -142,6 +149,10 @@ func getDB() *mw.DB {
 		os.Exit(-1)
 	}
 
+	if debug {
+		dbCon.AddQueryHook(mw.NewLogHook(log.New(os.Stdout, "", 0)))
+	}
+
 	db = dbCon
 	return dbCon
 }
@@ -150,11 +161,36 @@ func getDB() *mw.DB {
 See readme for details on generation
 */
 func Generate(args []string) error {
-	// Right now we only support `mwcmd gen init StructName`
-	if len(args) != 5 || args[2] != "init" {
-		return errors.New("gen command currently only supports `$ mwcmd gen init StructName shortName`")
+	if len(args) < 3 {
+		return errors.New("gen command requires init, diff or model subcommand")
+	}
+	switch args[2] {
+	case "init":
+		if len(args) != 5 {
+			return errors.New("usage: `$ mwcmd gen init StructName shortName`")
+		}
+		fmt.Println(mw.GenerateInit(args[3], args[4]))
+	case "diff":
+		if len(args) < 5 {
+			return errors.New("usage: `$ mwcmd gen diff github.com/you/app/models StructName...`")
+		}
+		fmt.Println(mw.GenerateDiff(args[3], args[4:]...))
+	case "model":
+		if len(args) < 5 || len(args) > 6 || args[3] != "--table" {
+			return errors.New("usage: `$ mwcmd gen model --table table_name [shortName]`")
+		}
+		var shortName string
+		if len(args) == 6 {
+			shortName = args[5]
+		}
+		model, err := mw.GenerateTableModel(getDB(), args[4], shortName)
+		if err != nil {
+			return err
+		}
+		fmt.Println(model)
+	default:
+		return fmt.Errorf("unknown gen subcommand (%s), init, diff or model expected", args[2])
 	}
-	fmt.Println(mw.GenerateInit(args[3], args[4]))
 	return nil
 }
 
@@ -1,40 +1,13 @@
 package mwear
 
 import (
+	"context"
+	"database/sql"
 	"encoding/json"
 	"fmt"
 	"reflect"
-
-	"github.com/go-sql-driver/mysql"
 )
 
-func IsUniqueViolationError(err error) bool {
-	if err == nil {
-		return false
-	}
-	if mysqlError, ok := err.(*mysql.MySQLError); ok {
-		if mysqlError.Number == 1062 { // duplicate entry error code for mysql
-			return true
-		}
-	}
-
-	return false
-}
-
-// IsTableExistsError checks whether an error is table already exists error.
-func IsTableExistsError(err error) bool {
-	if err == nil {
-		return false
-	}
-	if mysqlError, ok := err.(*mysql.MySQLError); ok {
-		if mysqlError.Number == 1050 { // table exists error code for mysql
-			return true
-		}
-	}
-
-	return false
-}
-
 type jsonScanner struct {
 	item interface{}
 }
@@ -61,155 +34,201 @@ func (scanner *jsonScanner) Scan(val interface{}) error {
 	}
 }
 
-func (a *Adapter) rawSelect(sqlStmt string, columns []string, joinMods []*model, joinFields [][]*field, requirePK bool, sliceValElement reflect.Value,
-	sliceTypeElement reflect.Type, args ...interface{}) error {
+func (a *Adapter) rawSelect(ctx context.Context, op, table, sqlStmt string, columns []string, joinMods []*model, joinFields [][]*field, requirePK bool, sliceValElement reflect.Value,
+	sliceTypeElement reflect.Type, args ...interface{}) (err error) {
 
-	if debugEnabled {
-		fmt.Println(sqlStmt, args)
-	}
+	var scanned int64
+	ctx, event := a.beforeQuery(ctx, op, table, sqlStmt, args)
+	defer func() {
+		a.afterQuery(ctx, event, scanned, err)
+	}()
 
-	rows, err := a.con.Query(sqlStmt, args...)
+	rows, err := a.query(ctx, op, table, sqlStmt, args...)
 	if err != nil {
 		return err
 	}
 
-	var prevRow struct {
-		modPK            string
-		model            reflect.Value
-		parsedJoinModels map[string][]string
-	}
-	var (
-		mod       *model
-		modFields []*field
-		modPK     string
-		valAddrs  []interface{}
-		rowModel  reflect.Value
-		rowJoins  []reflect.Value
-	)
+	var scanner *rowScanner
+	loadedFrom := sliceValElement.Len()
 
 	defer rows.Close()
 	for rows.Next() {
-		if mod == nil {
-			mod = parseModel(reflect.New(sliceTypeElement).Interface(), requirePK)
-			modFields = mod.getFields(columns)
-			valAddrs = make([]interface{}, 0, len(modFields))
-		} else {
-			valAddrs = valAddrs[:0]
-			rowJoins = rowJoins[:0]
-		}
-		rowModel = reflect.New(mod.ReflectType.Elem())
-		for i := range joinMods {
-			rowJoins = append(rowJoins, reflect.New(joinMods[i].ReflectType.Elem()))
-		}
-		for i := range modFields {
-			val := rowModel.Elem().Field(modFields[i].FieldPos).Addr().Interface()
-			if modFields[i].MWType == mw_json {
-				val = &jsonScanner{val}
-			} else if modFields[i].Nullable {
-				val = &nullScanner{rowModel.Elem().Field(modFields[i].FieldPos), modFields[i]}
+		if scanner == nil {
+			mod, err := parseModel(reflect.New(sliceTypeElement).Interface(), requirePK)
+			if err != nil {
+				return err
 			}
-			valAddrs = append(valAddrs, val)
-		}
-		for i := range joinMods {
-			for ind := range joinFields[i] {
-				fv := rowJoins[i].Elem().Field(joinFields[i][ind].FieldPos)
-				scanner := &nullScanner{fv, joinFields[i][ind]}
-				valAddrs = append(valAddrs, scanner)
+			scanner, err = newRowScanner(mod, columns, joinMods, joinFields)
+			if err != nil {
+				return err
 			}
 		}
 
-		err = rows.Scan(valAddrs...)
+		rowModel, isNew, err := scanner.scan(rows)
 		if err != nil {
-			panic(err)
+			return newQueryError(op, table, sqlStmt, fmt.Errorf("scan error: %w", err))
 		}
+		scanned++
 
-		if mod.PKPos != -1 {
-			modPK = mod.getPK(rowModel)
+		if isNew {
+			scanner.mod.takeSnapshot(rowModel)
+			// if our model is scanned first time, just append it to other models
+			sliceValElement.Set(reflect.Append(sliceValElement, rowModel.Elem()))
+		} else {
+			// if this model was already scanned, but some joins were added to it,
+			// we just want to update the latest slice element with newest changes.
+			sliceValElement.Index(sliceValElement.Len() - 1).Set(rowModel.Elem())
 		}
-		// rowIsTheSame is used for checks whether the next row represents the same
-		// model, and if yes it means that the difference is in the different join model,
-		// which happens in one to many relation.
-		rowIsTheSame := modPK != "" && modPK == prevRow.modPK
-		if len(joinMods) != 0 {
-			for i := range joinMods {
-				joinName := joinMods[i].ReflectType.Elem().Name()
-				joinPos, ok := mod.Joins[joinName]
-				if !ok {
-					panic(fmt.Sprintf("unknown join %s", joinMods[i].ReflectType.Elem().Name()))
-				}
-				modJoin := rowModel.Elem().Field(joinPos)
+	}
+	err = rows.Err()
 
-				var joinPKVal string
-				if joinMods[i].PKPos != -1 {
-					joinPKVal = joinMods[i].getPK(rowJoins[i])
-				}
+	if err != nil {
+		return newQueryError(op, table, sqlStmt, err)
+	}
 
-				// during join select we replace possible joined null values with default values,
-				// as pgx don't want to parse null into string), so we just check whether
-				// joined primary key is empty, which means that this row don't have anything joined.
-				if joinPKVal == "" {
-					continue
-				}
+	return afterLoadSlice(ctx, sliceValElement, loadedFrom)
+}
 
-				// if case its one to one join
-				if modJoin.Kind() != reflect.Slice {
-					if modJoin.Kind() == reflect.Ptr {
-						modJoin.Set(rowJoins[i])
-					} else {
-						modJoin.Set(rowJoins[i].Elem())
-					}
-					continue
-				}
+// rowScanner scans sql rows into models, merging joined rows of the same model.
+type rowScanner struct {
+	mod        *model
+	fields     []*field
+	joinMods   []*model
+	joinFields [][]*field
+
+	valAddrs []interface{}
+	rowJoins []reflect.Value
+
+	// prevPK and prevModel describe previously scanned model.
+	prevPK    string
+	prevModel reflect.Value
+	// parsedJoinModels keeps joined models already added to previous model.
+	parsedJoinModels map[string][]string
+}
 
-				// in case one-to-many join we want to ensure that we haven't already added this
-				// join to our model, thats why we keep added models in prevRow.parsedJoinModels map.
-				var modelAlreadySet bool
-				if prevRow.parsedJoinModels != nil {
-					if parsedModels, ok := prevRow.parsedJoinModels[joinName]; ok {
-						for _, mID := range parsedModels {
-							if mID == joinPKVal {
-								modelAlreadySet = true
-								break
-							}
-						}
+func newRowScanner(mod *model, columns []string, joinMods []*model, joinFields [][]*field) (*rowScanner, error) {
+	fields, err := mod.getFields(columns)
+	if err != nil {
+		return nil, err
+	}
+	return &rowScanner{
+		mod:        mod,
+		fields:     fields,
+		joinMods:   joinMods,
+		joinFields: joinFields,
+		valAddrs:   make([]interface{}, 0, len(fields)),
+		rowJoins:   make([]reflect.Value, 0, len(joinMods)),
+	}, nil
+}
+
+// scan scans current row into a new model. In case the row represents the same model as
+// the previous one, which happens in one to many join, joined models are added to
+// the previous model, and it is returned with isNew set to false.
+func (s *rowScanner) scan(rows *sql.Rows) (rowModel reflect.Value, isNew bool, err error) {
+	s.valAddrs = s.valAddrs[:0]
+	s.rowJoins = s.rowJoins[:0]
+
+	rowModel = reflect.New(s.mod.ReflectType.Elem())
+	for i := range s.joinMods {
+		s.rowJoins = append(s.rowJoins, reflect.New(s.joinMods[i].ReflectType.Elem()))
+	}
+	for i := range s.fields {
+		fv := s.fields[i].value(rowModel, true)
+		val := fv.Addr().Interface()
+		if s.fields[i].JSON && !s.fields[i].Ptr {
+			val = &jsonScanner{val}
+		} else if s.fields[i].Nullable {
+			val = &nullScanner{fv, s.fields[i]}
+		}
+		s.valAddrs = append(s.valAddrs, val)
+	}
+	for i := range s.joinMods {
+		for ind := range s.joinFields[i] {
+			fv := s.joinFields[i][ind].value(s.rowJoins[i], true)
+			scanner := &nullScanner{fv, s.joinFields[i][ind]}
+			s.valAddrs = append(s.valAddrs, scanner)
+		}
+	}
+
+	if err := rows.Scan(s.valAddrs...); err != nil {
+		return rowModel, false, err
+	}
+
+	var modPK string
+	if s.mod.PKName != "" {
+		modPK = s.mod.getPK(rowModel)
+	}
+	// rowIsTheSame is used for checks whether the next row represents the same
+	// model, and if yes it means that the difference is in the different join model,
+	// which happens in one to many relation.
+	rowIsTheSame := modPK != "" && modPK == s.prevPK && s.prevModel.IsValid()
+	if !rowIsTheSame {
+		s.parsedJoinModels = nil
+	}
+	for i := range s.joinMods {
+		joinName := s.joinMods[i].ReflectType.Elem().Name()
+		joinPos, ok := s.mod.Joins[joinName]
+		if !ok {
+			return rowModel, false, fmt.Errorf("unknown join %s", joinName)
+		}
+		modJoin := valueByIndex(rowModel, joinPos, true)
+
+		var joinPKVal string
+		if s.joinMods[i].PKName != "" {
+			joinPKVal = s.joinMods[i].getPK(s.rowJoins[i])
+		}
+
+		// during join select we replace possible joined null values with default values,
+		// as pgx don't want to parse null into string), so we just check whether
+		// joined primary key is empty, which means that this row don't have anything joined.
+		if joinPKVal == "" {
+			continue
+		}
+
+		// if case its one to one join
+		if modJoin.Kind() != reflect.Slice {
+			if modJoin.Kind() == reflect.Ptr {
+				modJoin.Set(s.rowJoins[i])
+			} else {
+				modJoin.Set(s.rowJoins[i].Elem())
+			}
+			continue
+		}
+
+		// in case one-to-many join we want to ensure that we haven't already added this
+		// join to our model, thats why we keep added models in parsedJoinModels map.
+		var modelAlreadySet bool
+		if s.parsedJoinModels != nil {
+			if parsedModels, ok := s.parsedJoinModels[joinName]; ok {
+				for _, mID := range parsedModels {
+					if mID == joinPKVal {
+						modelAlreadySet = true
+						break
 					}
-				} else {
-					prevRow.parsedJoinModels = make(map[string][]string)
-				}
-				if modelAlreadySet {
-					continue
-				}
-				prevRow.parsedJoinModels[joinName] = append(prevRow.parsedJoinModels[joinName], joinPKVal)
-
-				// set current join model to our real model.
-				if rowIsTheSame {
-					prevVal := prevRow.model.Elem().Field(joinPos)
-					prevVal.Set(reflect.Append(prevVal, rowJoins[i].Elem()))
-				} else {
-					slice := reflect.MakeSlice(reflect.SliceOf(joinMods[i].ReflectType.Elem()), 0, 1)
-					rowModel.Elem().Field(joinPos).Set(reflect.Append(slice, rowJoins[i].Elem()))
 				}
 			}
+		} else {
+			s.parsedJoinModels = make(map[string][]string)
 		}
-		if !rowIsTheSame {
-			prevRow.model = rowModel
-			prevRow.modPK = modPK
+		if modelAlreadySet {
+			continue
 		}
+		s.parsedJoinModels[joinName] = append(s.parsedJoinModels[joinName], joinPKVal)
 
-		if !rowIsTheSame {
-			// if our model is scanned first time, just append it to other models
-			sliceValElement.Set(reflect.Append(sliceValElement, rowModel.Elem()))
+		// set current join model to our real model.
+		if rowIsTheSame {
+			prevVal := valueByIndex(s.prevModel, joinPos, true)
+			prevVal.Set(reflect.Append(prevVal, s.rowJoins[i].Elem()))
 		} else {
-			// if this model was already scanned, but some joins were added to it,
-			// we just want to update the latest slice element with newest changes.
-			sliceValElement.Index(sliceValElement.Len() - 1).Set(prevRow.model.Elem())
+			slice := reflect.MakeSlice(reflect.SliceOf(s.joinMods[i].ReflectType.Elem()), 0, 1)
+			valueByIndex(rowModel, joinPos, true).Set(reflect.Append(slice, s.rowJoins[i].Elem()))
 		}
 	}
-	err = rows.Err()
-
-	if err != nil {
-		return err
+	if rowIsTheSame {
+		return s.prevModel, false, nil
 	}
 
-	return nil
+	s.prevModel = rowModel
+	s.prevPK = modPK
+	return rowModel, true, nil
 }
@@ -1,12 +1,21 @@
 package mwear
 
 import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"math"
 	"math/rand"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/cliqueinc/mysql-wear/sqlq"
 	"github.com/cliqueinc/mysql-wear/util" // Hopefully our only pgc dep
+	"github.com/go-sql-driver/mysql"
 )
 
 // Please see testing guidelines in the readme
@@ -98,6 +107,24 @@ func TestMustSelectPanicNoSlicePtr(t *testing.T) {
 	}()
 }
 
+func TestSelectInvalidDest(t *testing.T) {
+	db.MustCreateTable(&selectTest{})
+	var s selectTest
+	var nilSlice *[]selectTest
+	for name, dest := range map[string]interface{}{
+		"struct ptr":    &s,
+		"nil":           nil,
+		"nil slice ptr": nilSlice,
+	} {
+		if err := db.Select(dest); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+		if _, err := db.SelectPage(dest, sqlq.Order("id", sqlq.ASC)); err == nil {
+			t.Errorf("%s: expected page error", name)
+		}
+	}
+}
+
 func TestSelectColumns(t *testing.T) {
 	s := &selectTest{}
 
@@ -109,17 +136,20 @@ func TestSelectColumns(t *testing.T) {
 	db.MustInsert(s)
 
 	t.Run("unknown column", func(t *testing.T) {
+		var sl []selectTest
+		err := db.Select(&sl, sqlq.Columns("some column", "id"), sqlq.Limit(2))
+		if err == nil {
+			t.Errorf("error expected in case some column isn't recognized")
+		}
+		if _, err := db.Get(&selectTest{}, sqlq.Columns("some column"), sqlq.Equal("id", s.ID)); err == nil {
+			t.Errorf("error expected in case some column isn't recognized")
+		}
 		defer func() {
 			if r := recover(); r == nil {
 				t.Errorf("panic expected in case some column isn't recognized")
 			}
 		}()
-
-		var sl []selectTest
-		err := db.Select(&sl, sqlq.Columns("some column", "id"), sqlq.Limit(2))
-		if err != nil {
-			t.Errorf("unexpected error: %v", err)
-		}
+		db.MustSelect(&sl, sqlq.Columns("some column", "id"), sqlq.Limit(2))
 	})
 	t.Run("custom columns", func(t *testing.T) {
 		var sl []selectTest
@@ -499,6 +529,20 @@ func TestInsert(t *testing.T) {
 	if !IsUniqueViolationError(err) {
 		t.Errorf("TestInsertWErr InsertErrS expected code for unique violation, was (%s)", err)
 	}
+	if !errors.Is(err, ErrUniqueViolation) {
+		t.Errorf("expected error to be unique violation, was (%s)", err)
+	}
+	var queryErr *QueryError
+	if !errors.As(err, &queryErr) {
+		t.Fatalf("expected query error, got (%T)", err)
+	}
+	if queryErr.Op != OpInsert || queryErr.Table != "fake_insert2" || queryErr.SQL == "" {
+		t.Errorf("unexpected query error (%#v)", queryErr)
+	}
+	var mysqlErr *mysql.MySQLError
+	if !errors.As(err, &mysqlErr) || mysqlErr.Number != 1062 {
+		t.Errorf("expected original mysql error to be kept, got (%#v)", mysqlErr)
+	}
 
 	t.Run("insert more that limit allows", func(t *testing.T) {
 		items := make([]interface{}, 0, LimitInsert+1)
@@ -512,6 +556,225 @@ func TestInsert(t *testing.T) {
 	})
 }
 
+func TestInsertAutoIncrementID(t *testing.T) {
+	type fakeAutoID struct {
+		ID   int64
+		Name string
+	}
+	db.MustCreateTable(&fakeAutoID{})
+
+	f1 := &fakeAutoID{Name: "Bob"}
+	db.MustInsert(f1)
+	if f1.ID == 0 {
+		t.Fatalf("id expected to be set after insert")
+	}
+
+	f2 := &fakeAutoID{Name: "John"}
+	f3 := &fakeAutoID{Name: "James"}
+	db.MustInsert(f2, f3)
+	if f2.ID != f1.ID+1 || f3.ID != f1.ID+2 {
+		t.Fatalf("consecutive ids expected after (%d), actual: (%d), (%d)", f1.ID, f2.ID, f3.ID)
+	}
+
+	f3Get := &fakeAutoID{ID: f3.ID}
+	if found := db.MustGet(f3Get); !found || f3Get.Name != f3.Name {
+		t.Errorf("row with id (%d) expected to be (%s), actual: (%s)", f3.ID, f3.Name, f3Get.Name)
+	}
+}
+
+func TestInsertUnsignedAutoIncrementID(t *testing.T) {
+	type fakeUnsignedID struct {
+		ID   uint64
+		Name string
+	}
+	db.MustCreateTable(&fakeUnsignedID{})
+
+	f1 := &fakeUnsignedID{Name: "Bob"}
+	f2 := &fakeUnsignedID{Name: "John"}
+	db.MustInsert(f1, f2)
+	if f1.ID == 0 || f2.ID != f1.ID+1 {
+		t.Fatalf("consecutive ids expected, actual: (%d), (%d)", f1.ID, f2.ID)
+	}
+
+	f2Get := &fakeUnsignedID{ID: f2.ID}
+	if found := db.MustGet(f2Get); !found || f2Get.Name != f2.Name {
+		t.Errorf("row with id (%d) expected to be (%s), actual: (%s)", f2.ID, f2.Name, f2Get.Name)
+	}
+	db.MustDelete(f2Get)
+	if found := db.MustGet(&fakeUnsignedID{ID: f2.ID}); found {
+		t.Errorf("row with id (%d) expected to be deleted", f2.ID)
+	}
+}
+
+func TestInsertBatch(t *testing.T) {
+	type fakeBatch struct {
+		ID   int
+		Name string
+	}
+	db.MustCreateTable(&fakeBatch{})
+
+	t.Run("more than limit", func(t *testing.T) {
+		items := make([]fakeBatch, 0, 2*LimitInsert+5)
+		for i := 0; i < 2*LimitInsert+5; i++ {
+			items = append(items, fakeBatch{Name: RandomString(10)})
+		}
+		res, err := db.InsertBatch(items)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if res.Chunks != 3 {
+			t.Errorf("expected 3 chunks, actual: (%d)", res.Chunks)
+		}
+		if res.RowsAffected != int64(len(items)) {
+			t.Errorf("expected (%d) rows inserted, actual: (%d)", len(items), res.RowsAffected)
+		}
+		if res.FirstInsertID == 0 {
+			t.Errorf("first insert id expected to be set")
+		}
+		if count := db.MustCount(&fakeBatch{}); count != len(items) {
+			t.Errorf("expected (%d) rows in table, actual: (%d)", len(items), count)
+		}
+	})
+	t.Run("max bytes", func(t *testing.T) {
+		items := []*fakeBatch{
+			{Name: RandomString(100)},
+			{Name: RandomString(100)},
+			{Name: RandomString(100)},
+		}
+		res, err := db.InsertBatch(items, BatchMaxBytes(150), BatchInTx())
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if res.Chunks != 3 {
+			t.Errorf("expected each row inserted by separate query, actual chunks: (%d)", res.Chunks)
+		}
+	})
+	t.Run("rollback on error", func(t *testing.T) {
+		before := db.MustCount(&fakeBatch{})
+		items := []*fakeBatch{
+			{Name: RandomString(10)},
+			{Name: RandomString(10)},
+			{ID: 1, Name: RandomString(10)},
+		}
+		if _, err := db.InsertBatch(items, BatchSize(2), BatchInTx()); err == nil {
+			t.Fatalf("duplicate primary key error expected")
+		}
+		if count := db.MustCount(&fakeBatch{}); count != before {
+			t.Errorf("inserted rows expected to be rolled back, rows before: (%d), after: (%d)", before, count)
+		}
+		if items[0].ID != 0 || items[1].ID != 0 {
+			t.Errorf("ids of rolled back rows expected to be reset, got (%d) and (%d)", items[0].ID, items[1].ID)
+		}
+	})
+	t.Run("partial result on error", func(t *testing.T) {
+		items := []*fakeBatch{
+			{Name: RandomString(10)},
+			{Name: RandomString(10)},
+			{ID: 1, Name: RandomString(10)},
+		}
+		res, err := db.InsertBatch(items, BatchSize(2))
+		if err == nil {
+			t.Fatalf("duplicate primary key error expected")
+		}
+		if res == nil || res.Chunks != 1 || res.RowsAffected != 2 {
+			t.Fatalf("result of the first chunk expected along with the error, got (%+v)", res)
+		}
+	})
+}
+
+func TestUpsert(t *testing.T) {
+	type fakeUpsert struct {
+		ID     string
+		Name   string
+		Visits int
+	}
+	f1 := &fakeUpsert{ID: RandomString(25), Name: "Bob", Visits: 1}
+	f2 := &fakeUpsert{ID: RandomString(25), Name: "John", Visits: 1}
+	db.MustCreateTable(f1)
+	db.MustInsert(f1)
+
+	t.Run("upsert all columns", func(t *testing.T) {
+		f1.Name = "Bob2"
+		res, err := db.Upsert(f1, f2)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if res.Inserted != 1 || res.Updated != 1 {
+			t.Errorf("expected 1 inserted and 1 updated row, actual: (%d) inserted, (%d) updated", res.Inserted, res.Updated)
+		}
+		f1Get := &fakeUpsert{ID: f1.ID}
+		db.MustGet(f1Get)
+		if f1Get.Name != f1.Name {
+			t.Errorf("name expected to be updated to (%s), actual: (%s)", f1.Name, f1Get.Name)
+		}
+		if found := db.MustGet(&fakeUpsert{ID: f2.ID}); !found {
+			t.Errorf("f2 wasn't inserted")
+		}
+	})
+	t.Run("upsert columns", func(t *testing.T) {
+		f1.Name = "Bob3"
+		f1.Visits = 10
+		if _, err := db.UpsertColumns([]string{"visits"}, f1); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		f1Get := &fakeUpsert{ID: f1.ID}
+		db.MustGet(f1Get)
+		if f1Get.Visits != 10 {
+			t.Errorf("visits expected to be updated")
+		}
+		if f1Get.Name != "Bob2" {
+			t.Errorf("name shouldn't be updated, actual: (%s)", f1Get.Name)
+		}
+	})
+	t.Run("upsert map", func(t *testing.T) {
+		res, err := db.UpsertMap(Map{"visits": Expr("`visits` + 1"), "name": "Bob4"}, f1)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if res.Updated != 1 {
+			t.Errorf("expected 1 updated row, actual: (%d)", res.Updated)
+		}
+		f1Get := &fakeUpsert{ID: f1.ID}
+		db.MustGet(f1Get)
+		if f1Get.Visits != 11 || f1Get.Name != "Bob4" {
+			t.Errorf("row wasn't updated by map data: %+v", f1Get)
+		}
+	})
+}
+
+func TestUpsertVersion(t *testing.T) {
+	type versionedUpsert struct {
+		ID      string
+		Name    string
+		Version int64 `mw:"version"`
+	}
+	db.MustCreateTable(&versionedUpsert{})
+
+	doc := &versionedUpsert{ID: RandomString(10), Name: "draft"}
+	db.MustInsert(doc)
+	doc.Name = "first"
+	db.MustUpdate(doc)
+
+	db.MustUpsert(&versionedUpsert{ID: doc.ID, Name: "upserted"})
+	loaded := &versionedUpsert{ID: doc.ID}
+	db.MustGet(loaded)
+	if loaded.Name != "upserted" || loaded.Version != 1 {
+		t.Fatalf("upsert expected to keep version 1, actual (%+v)", loaded)
+	}
+	doc.Name = "second"
+	if err := db.Update(doc); err != nil {
+		t.Errorf("update after upsert expected to match kept version, got (%v)", err)
+	}
+
+	if _, err := db.UpsertMap(Map{"version": Expr("`version` + 1")}, &versionedUpsert{ID: doc.ID}); err != nil {
+		t.Fatalf("fail upsert map: %v", err)
+	}
+	db.MustGet(loaded)
+	if loaded.Version != doc.Version+1 {
+		t.Errorf("upsert map expected to increment version to (%d), actual (%d)", doc.Version+1, loaded.Version)
+	}
+}
+
 func TestUpdate(t *testing.T) {
 	type fakeUpdate struct {
 		ID        string
@@ -581,6 +844,543 @@ func TestUpdate(t *testing.T) {
 	})
 }
 
+func TestTimestamps(t *testing.T) {
+	type stampedUser struct {
+		ID      string
+		Name    string
+		Created time.Time `mw:"created"`
+		Updated time.Time `mw:"updated"`
+	}
+	now := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	clockDB := New(db.DB)
+	clockDB.SetClock(func() time.Time { return now })
+	clockDB.MustCreateTable(&stampedUser{})
+
+	u := &stampedUser{ID: RandomString(10), Name: "first"}
+	clockDB.MustInsert(u)
+	if !u.Created.Equal(now) || !u.Updated.Equal(now) {
+		t.Fatalf("insert expected to set created and updated to (%v), got (%+v)", now, u)
+	}
+
+	created := now
+	now = now.Add(time.Hour)
+	u.Name = "updated"
+	clockDB.MustUpdate(u)
+	if !u.Created.Equal(created) || !u.Updated.Equal(now) {
+		t.Fatalf("update expected to set only updated to (%v), got (%+v)", now, u)
+	}
+
+	loaded := &stampedUser{ID: u.ID}
+	clockDB.MustGet(loaded)
+	if !loaded.Created.Equal(created) || !loaded.Updated.Equal(now) {
+		t.Errorf("expected stored timestamps (%v, %v), got (%+v)", created, now, loaded)
+	}
+
+	now = now.Add(time.Hour)
+	clockDB.MustUpdateRows(&stampedUser{}, Map{"name": "rows"}, sqlq.Equal("id", u.ID))
+	clockDB.MustGet(loaded)
+	if loaded.Name != "rows" || !loaded.Updated.Equal(now) || !loaded.Created.Equal(created) {
+		t.Errorf("update rows expected to set updated to (%v), got (%+v)", now, loaded)
+	}
+
+	now = now.Add(time.Hour)
+	clockDB.MustUpsert(&stampedUser{ID: u.ID, Name: "upserted"})
+	clockDB.MustGet(loaded)
+	if loaded.Name != "upserted" || !loaded.Updated.Equal(now) || !loaded.Created.Equal(created) {
+		t.Errorf("upsert expected to keep created (%v) and set updated (%v), got (%+v)", created, now, loaded)
+	}
+
+	if _, err := db.DB.Exec("INSERT INTO stamped_user (id, name) VALUES (?, ?)", "raw", "raw"); err != nil {
+		t.Fatalf("fail insert raw row: %v", err)
+	}
+	raw := &stampedUser{ID: "raw"}
+	clockDB.MustGet(raw)
+	if raw.Created.IsZero() || raw.Updated.IsZero() {
+		t.Errorf("columns expected to default to current timestamp, got (%+v)", raw)
+	}
+}
+
+type hookStampedUser struct {
+	ID      int64
+	Created time.Time `mw:"created"`
+	Updated time.Time `mw:"updated"`
+}
+
+func (u *hookStampedUser) BeforeInsert(ctx context.Context) error {
+	u.Created = time.Time{}
+	u.Updated = time.Time{}
+	return nil
+}
+
+func (u *hookStampedUser) BeforeUpdate(ctx context.Context) error {
+	u.Updated = time.Time{}
+	return nil
+}
+
+func TestTimestampsAfterHooks(t *testing.T) {
+	now := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	clockDB := New(db.DB)
+	clockDB.SetClock(func() time.Time { return now })
+	clockDB.MustCreateTable(&hookStampedUser{})
+
+	u := &hookStampedUser{}
+	clockDB.MustInsert(u)
+	if !u.Created.Equal(now) || !u.Updated.Equal(now) {
+		t.Fatalf("insert expected to set timestamps after hooks to (%v), got (%+v)", now, u)
+	}
+	now = now.Add(time.Hour)
+	clockDB.MustUpdate(u)
+	if !u.Updated.Equal(now) {
+		t.Fatalf("update expected to set updated after hook to (%v), got (%+v)", now, u)
+	}
+}
+
+func TestUpdateColumns(t *testing.T) {
+	type partialUser struct {
+		ID    string
+		Name  string
+		Email string
+	}
+	db.MustCreateTable(&partialUser{})
+	u := &partialUser{ID: RandomString(10), Name: "name", Email: "email"}
+	db.MustInsert(u)
+
+	// another service changes email meanwhile
+	db.MustUpdateRows(&partialUser{}, Map{"email": "new email"}, sqlq.Equal("id", u.ID))
+
+	u.Name = "new name"
+	if err := db.UpdateColumns(u, "name"); err != nil {
+		t.Fatalf("fail update columns: %v", err)
+	}
+	loaded := &partialUser{ID: u.ID}
+	db.MustGet(loaded)
+	if loaded.Name != "new name" || loaded.Email != "new email" {
+		t.Errorf("expected only name to be updated, got (%+v)", loaded)
+	}
+
+	if err := db.UpdateColumns(u); err == nil {
+		t.Errorf("update without columns expected to fail")
+	}
+	if err := db.UpdateColumns(u, "unknown"); err == nil {
+		t.Errorf("update of unknown column expected to fail")
+	}
+}
+
+type trackedUser struct {
+	Tracked
+	ID    string
+	Name  string
+	Email string
+	Tags  []string
+}
+
+func TestDirtyTracking(t *testing.T) {
+	hook := &recordHook{}
+	trackDB := New(db.DB)
+	trackDB.AddQueryHook(hook)
+	trackDB.MustCreateTable(&trackedUser{})
+
+	u := &trackedUser{ID: RandomString(10), Name: "name", Email: "email", Tags: []string{"a"}}
+	trackDB.MustInsert(u)
+
+	loaded := &trackedUser{ID: u.ID}
+	trackDB.MustGet(loaded)
+	loaded.Name = "new name"
+	trackDB.MustUpdate(loaded)
+	if e := hook.last(); e.Op != OpUpdate || strings.Contains(e.SQL, "`email`") || strings.Contains(e.SQL, "`tags`") || len(e.Args) != 2 {
+		t.Errorf("expected only name to be updated (%s), args (%v)", e.SQL, e.Args)
+	}
+
+	// not changed model isn't updated at all
+	eventsNum := len(hook.events)
+	trackDB.MustUpdate(loaded)
+	if len(hook.events) != eventsNum {
+		t.Errorf("update of not changed model expected to be skipped, got (%+v)", hook.last())
+	}
+
+	var users []trackedUser
+	trackDB.MustSelect(&users, sqlq.Equal("id", u.ID))
+	if len(users) != 1 {
+		t.Fatalf("expected 1 user, actual (%d)", len(users))
+	}
+	users[0].Tags = append(users[0].Tags, "b")
+	trackDB.MustUpdate(&users[0])
+	if e := hook.last(); !strings.Contains(e.SQL, "`tags`") || strings.Contains(e.SQL, "`name`") {
+		t.Errorf("expected only tags to be updated (%s)", e.SQL)
+	}
+
+	it, err := trackDB.Iterate(&trackedUser{}, sqlq.Equal("id", u.ID))
+	if err != nil {
+		t.Fatalf("fail iterate: %v", err)
+	}
+	var iterated trackedUser
+	for it.Next() {
+		if err := it.Scan(&iterated); err != nil {
+			t.Fatalf("fail scan: %v", err)
+		}
+	}
+	if err := it.Err(); err != nil {
+		t.Fatalf("iteration error: %v", err)
+	}
+	iterated.Email = "new email"
+	trackDB.MustUpdate(&iterated)
+	if e := hook.last(); !strings.Contains(e.SQL, "`email`") || strings.Contains(e.SQL, "`name`") {
+		t.Errorf("expected only email of iterated user to be updated (%s)", e.SQL)
+	}
+
+	// model without snapshot is updated fully
+	trackDB.MustUpdate(&trackedUser{ID: u.ID, Name: "full"})
+	if e := hook.last(); !strings.Contains(e.SQL, "`email`") || !strings.Contains(e.SQL, "`name`") {
+		t.Errorf("expected all columns to be updated (%s)", e.SQL)
+	}
+	trackDB.MustGet(loaded)
+	if loaded.Name != "full" || loaded.Email != "" || len(loaded.Tags) != 0 {
+		t.Errorf("unexpected user (%+v)", loaded)
+	}
+}
+
+func TestUpdateVersion(t *testing.T) {
+	type versionedDoc struct {
+		ID      string
+		Title   string
+		Version int64 `mw:"version"`
+	}
+	db.MustCreateTable(&versionedDoc{})
+
+	doc := &versionedDoc{ID: RandomString(10), Title: "draft"}
+	db.MustInsert(doc)
+
+	first := &versionedDoc{ID: doc.ID}
+	second := &versionedDoc{ID: doc.ID}
+	db.MustGet(first)
+	db.MustGet(second)
+
+	first.Title = "first"
+	if err := db.Update(first); err != nil {
+		t.Fatalf("fail update: %v", err)
+	}
+	if first.Version != 1 {
+		t.Errorf("expected version 1, actual (%d)", first.Version)
+	}
+
+	second.Title = "second"
+	err := db.Update(second)
+	if !errors.Is(err, ErrStaleObject) || !IsStaleObjectError(err) {
+		t.Fatalf("expected stale object error, got (%v)", err)
+	}
+	if second.Version != 0 {
+		t.Errorf("version of failed update expected to be kept, actual (%d)", second.Version)
+	}
+
+	db.MustGet(second)
+	if second.Title != "first" || second.Version != 1 {
+		t.Errorf("expected row updated by first (%+v)", second)
+	}
+	second.Title = "second"
+	db.MustUpdate(second)
+	if second.Version != 2 {
+		t.Errorf("expected version 2, actual (%d)", second.Version)
+	}
+
+	if err := db.Update(&versionedDoc{ID: "unknown"}); !errors.Is(err, ErrStaleObject) {
+		t.Errorf("update of missing row expected to fail with stale object error, got (%v)", err)
+	}
+}
+
+func TestUpdateVersionKeepsUpdated(t *testing.T) {
+	type stampedDoc struct {
+		ID      string
+		Title   string
+		Updated time.Time `mw:"updated"`
+		Version int64     `mw:"version"`
+	}
+	now := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	clockDB := New(db.DB)
+	clockDB.SetClock(func() time.Time { return now })
+	clockDB.MustCreateTable(&stampedDoc{})
+
+	doc := &stampedDoc{ID: RandomString(10), Title: "draft"}
+	clockDB.MustInsert(doc)
+	stale := &stampedDoc{ID: doc.ID}
+	clockDB.MustGet(stale)
+	doc.Title = "first"
+	clockDB.MustUpdate(doc)
+
+	now = now.Add(time.Hour)
+	stale.Title = "second"
+	if err := clockDB.Update(stale); !errors.Is(err, ErrStaleObject) {
+		t.Fatalf("expected stale object error, got (%v)", err)
+	}
+	if !stale.Updated.Equal(now.Add(-time.Hour)) {
+		t.Errorf("updated time of failed update expected to be kept, actual (%v)", stale.Updated)
+	}
+}
+
+func TestCompositePK(t *testing.T) {
+	type membership struct {
+		UserID  string `mw:"pk"`
+		GroupID int    `mw:"pk"`
+		Role    string
+	}
+	db.MustCreateTable(&membership{})
+
+	userID := RandomString(10)
+	db.MustInsert(
+		&membership{UserID: userID, GroupID: 1, Role: "owner"},
+		&membership{UserID: userID, GroupID: 2, Role: "member"},
+	)
+
+	m := &membership{UserID: userID, GroupID: 2}
+	if found := db.MustGet(m); !found || m.Role != "member" {
+		t.Fatalf("expected membership found by composite key (%+v)", m)
+	}
+	if found := db.MustGet(&membership{UserID: userID, GroupID: 3}); found {
+		t.Fatalf("membership with unknown group expected to be missing")
+	}
+
+	m.Role = "admin"
+	db.MustUpdate(m)
+	first := &membership{UserID: userID, GroupID: 1}
+	db.MustGet(first)
+	if first.Role != "owner" {
+		t.Errorf("update expected to change only one row, actual (%+v)", first)
+	}
+
+	var memberships []membership
+	pageOpts := []sqlq.Option{sqlq.Equal("user_id", userID), sqlq.Limit(1)}
+	page, err := db.SelectPage(&memberships, pageOpts...)
+	if err != nil {
+		t.Fatalf("fail select page: %v", err)
+	}
+	if page.Next == "" || len(memberships) != 1 || memberships[0].GroupID != 1 {
+		t.Fatalf("expected first page with group 1 and next cursor (%+v)", memberships)
+	}
+	memberships = nil
+	if _, err := db.SelectPage(&memberships, append(pageOpts, sqlq.After(page.Next))...); err != nil {
+		t.Fatalf("fail select page: %v", err)
+	}
+	if len(memberships) != 1 || memberships[0].GroupID != 2 {
+		t.Errorf("expected second page with group 2 (%+v)", memberships)
+	}
+
+	db.MustDelete(first)
+	if cnt := db.MustCount(&membership{}, sqlq.Equal("user_id", userID)); cnt != 1 {
+		t.Errorf("expected 1 membership left, actual (%d)", cnt)
+	}
+	if err := db.Delete(&membership{UserID: userID}); err == nil {
+		t.Errorf("delete with incomplete primary key expected to fail")
+	}
+}
+
+// money is amount in cents stored as DECIMAL, it implements sql.Scanner and driver.Valuer.
+type money int64
+
+func (m money) Value() (driver.Value, error) {
+	return fmt.Sprintf("%d.%02d", m/100, m%100), nil
+}
+
+func (m *money) Scan(val interface{}) error {
+	b, ok := val.([]byte)
+	if !ok {
+		return fmt.Errorf("cannot scan (%T) into money", val)
+	}
+	amount, err := strconv.ParseFloat(string(b), 64)
+	if err != nil {
+		return err
+	}
+	*m = money(math.Round(amount * 100))
+	return nil
+}
+
+type orderStatus struct {
+	name string
+}
+
+func (s orderStatus) Value() (driver.Value, error) {
+	if s.name == "" {
+		return nil, errors.New("order status is not set")
+	}
+	return s.name, nil
+}
+
+func (s *orderStatus) Scan(val interface{}) error {
+	b, ok := val.([]byte)
+	if !ok {
+		return fmt.Errorf("cannot scan (%T) into order status", val)
+	}
+	s.name = string(b)
+	return nil
+}
+
+func TestCustomTypes(t *testing.T) {
+	type customOrder struct {
+		ID     string
+		Price  money `mw:"type:DECIMAL(12,2)"`
+		Status orderStatus
+		Note   sql.NullString
+	}
+	db.MustCreateTable(&customOrder{})
+
+	o := &customOrder{ID: RandomString(10), Price: 1250, Status: orderStatus{"new"}}
+	db.MustInsert(o)
+
+	loaded := &customOrder{ID: o.ID}
+	db.MustGet(loaded)
+	if loaded.Price != 1250 || loaded.Status.name != "new" || loaded.Note.Valid {
+		t.Fatalf("expected order (%+v), actual (%+v)", o, loaded)
+	}
+
+	loaded.Price = 99
+	loaded.Note = sql.NullString{String: "gift", Valid: true}
+	db.MustUpdate(loaded)
+
+	var orders []customOrder
+	db.MustSelect(&orders, sqlq.Equal("price", money(99)))
+	if len(orders) != 1 || orders[0].Note.String != "gift" || orders[0].Status.name != "new" {
+		t.Fatalf("expected updated order, actual (%+v)", orders)
+	}
+
+	// NULL resets values of the struct loaded before.
+	db.MustUpdateRows(&customOrder{}, Map{"note": nil}, sqlq.Equal("id", o.ID))
+	db.MustGet(loaded)
+	if loaded.Note.Valid || loaded.Note.String != "" {
+		t.Fatalf("expected NULL note to reset loaded value, actual (%+v)", loaded.Note)
+	}
+
+	if _, err := db.Insert(&customOrder{ID: RandomString(10)}); err == nil {
+		t.Errorf("expected error of invalid status value")
+	}
+}
+
+func TestPointerFields(t *testing.T) {
+	type ptrProfile struct {
+		ID     string
+		UserID string
+		Bio    *string
+	}
+	type ptrUser struct {
+		ID       string
+		Age      *int64
+		Active   *bool
+		Birthday *time.Time
+		Tags     *[]string
+		Profile  *ptrProfile `mw:"join"`
+	}
+	db.MustCreateTable(&ptrUser{})
+	db.MustCreateTable(&ptrProfile{})
+
+	age, active := int64(0), true
+	birthday := time.Date(2000, 1, 2, 0, 0, 0, 0, time.UTC)
+	u := &ptrUser{ID: RandomString(10), Age: &age, Active: &active, Birthday: &birthday, Tags: &[]string{"a"}}
+	empty := &ptrUser{ID: RandomString(10)}
+	db.MustInsert(u, empty)
+
+	loaded := &ptrUser{ID: u.ID}
+	db.MustGet(loaded)
+	if loaded.Age == nil || *loaded.Age != 0 || loaded.Active == nil || !*loaded.Active ||
+		loaded.Birthday == nil || !loaded.Birthday.Equal(birthday) || loaded.Tags == nil || (*loaded.Tags)[0] != "a" {
+		t.Fatalf("expected user (%+v), actual (%+v)", u, loaded)
+	}
+	if cnt := db.MustCount(&ptrUser{}, sqlq.Raw("age IS NULL")); cnt != 1 {
+		t.Errorf("expected 1 user with NULL age, actual (%d)", cnt)
+	}
+
+	loaded.Age = nil
+	db.MustUpdate(loaded)
+	db.MustGet(loaded)
+	if loaded.Age != nil {
+		t.Errorf("expected NULL age after update, actual (%d)", *loaded.Age)
+	}
+
+	bio := "about"
+	db.MustInsert(&ptrProfile{ID: RandomString(10), UserID: u.ID, Bio: &bio})
+	var users []ptrUser
+	db.MustSelect(
+		&users,
+		sqlq.Join(&ptrProfile{}, "ptr_user.id = ptr_profile.user_id", "id", "bio"),
+		sqlq.Raw("ptr_user.id IN (?, ?)", u.ID, empty.ID),
+	)
+	if len(users) != 2 {
+		t.Fatalf("expected 2 users, actual (%d)", len(users))
+	}
+	for _, user := range users {
+		switch user.ID {
+		case u.ID:
+			if user.Profile == nil || user.Profile.Bio == nil || *user.Profile.Bio != bio {
+				t.Errorf("expected joined profile with bio, actual (%+v)", user.Profile)
+			}
+		case empty.ID:
+			if user.Age != nil || user.Active != nil || user.Birthday != nil || user.Tags != nil || user.Profile != nil {
+				t.Errorf("expected user with nil fields, actual (%+v)", user)
+			}
+		}
+	}
+}
+
+type embeddedBase struct {
+	ID      int64
+	Created time.Time `mw:"created"`
+	Updated time.Time `mw:"updated"`
+}
+
+type EmbeddedAudit struct {
+	Author string
+}
+
+func TestEmbeddedStruct(t *testing.T) {
+	type embeddedComment struct {
+		embeddedBase
+		PostID int64
+		Text   string
+	}
+	type embeddedPost struct {
+		embeddedBase
+		*EmbeddedAudit
+		Title    string
+		Comments []embeddedComment `mw:"join"`
+	}
+	db.MustCreateTable(&embeddedPost{})
+	db.MustCreateTable(&embeddedComment{})
+
+	p := &embeddedPost{Title: "first", EmbeddedAudit: &EmbeddedAudit{Author: "bob"}}
+	db.MustInsert(p)
+	if p.ID == 0 || p.Created.IsZero() {
+		t.Fatalf("expected id and timestamps of embedded struct set (%+v)", p)
+	}
+	noAudit := &embeddedPost{Title: "second"}
+	db.MustInsert(noAudit)
+
+	loaded := &embeddedPost{}
+	loaded.ID = p.ID
+	db.MustGet(loaded)
+	if loaded.Title != "first" || loaded.EmbeddedAudit == nil || loaded.Author != "bob" {
+		t.Fatalf("expected post (%+v), actual (%+v)", p, loaded)
+	}
+
+	loaded.Author = "alice"
+	db.MustUpdate(loaded)
+	db.MustInsert(
+		&embeddedComment{PostID: p.ID, Text: "a"},
+		&embeddedComment{PostID: p.ID, Text: "b"},
+	)
+
+	var posts []embeddedPost
+	db.MustSelect(
+		&posts,
+		sqlq.Join(&embeddedComment{}, "embedded_post.id = embedded_comment.post_id", "id", "text"),
+		sqlq.Order("`embedded_post`.`id`", sqlq.ASC),
+	)
+	if len(posts) != 2 {
+		t.Fatalf("expected 2 posts, actual (%d)", len(posts))
+	}
+	if posts[0].Author != "alice" || len(posts[0].Comments) != 2 || posts[0].Comments[0].ID == 0 {
+		t.Errorf("expected updated post with comments (%+v)", posts[0])
+	}
+	if posts[1].EmbeddedAudit == nil || posts[1].Author != "" || len(posts[1].Comments) != 0 {
+		t.Errorf("expected post without author and comments (%+v)", posts[1])
+	}
+}
+
 func TestDelete(t *testing.T) {
 	type fakeDelete struct {
 		ID        string
@@ -642,6 +1442,86 @@ func TestDelete(t *testing.T) {
 	})
 }
 
+func TestSoftDelete(t *testing.T) {
+	type softUser struct {
+		ID        string
+		Name      string
+		DeletedAt time.Time `mw:"soft_delete"`
+	}
+	db.MustCreateTable(&softUser{})
+
+	u1 := &softUser{ID: "u1", Name: "first"}
+	u2 := &softUser{ID: "u2", Name: "second"}
+	u3 := &softUser{ID: "u3", Name: "third"}
+	db.MustInsert(u1, u2, u3)
+
+	db.MustDelete(u1)
+	if u1.DeletedAt.IsZero() {
+		t.Errorf("deletion time expected to be set")
+	}
+	again := &softUser{ID: "u1"}
+	db.MustDelete(again)
+	missing := &softUser{ID: "missing"}
+	db.MustDelete(missing)
+	if !again.DeletedAt.IsZero() || !missing.DeletedAt.IsZero() {
+		t.Errorf("deletion time expected to be set only for deleted row (%+v, %+v)", again, missing)
+	}
+	if cnt := db.MustCount(&softUser{}); cnt != 2 {
+		t.Errorf("expected 2 not deleted users, actual (%d)", cnt)
+	}
+	if cnt := db.MustCount(&softUser{}, sqlq.WithDeleted()); cnt != 3 {
+		t.Errorf("expected 3 users with deleted, actual (%d)", cnt)
+	}
+	if found := db.MustGet(&softUser{ID: "u1"}); found {
+		t.Errorf("soft deleted user expected not to be found")
+	}
+	deleted := &softUser{ID: "u1"}
+	if found := db.MustGet(deleted, sqlq.WithDeleted()); !found || deleted.DeletedAt.IsZero() {
+		t.Errorf("soft deleted user expected to be found with deleted (%+v)", deleted)
+	}
+
+	var users []softUser
+	db.MustSelect(&users, sqlq.Order("id", sqlq.ASC))
+	if len(users) != 2 || users[0].ID != "u2" {
+		t.Errorf("expected users (u2, u3), actual (%+v)", users)
+	}
+	db.MustSelect(&users, sqlq.OnlyDeleted())
+	if len(users) != 1 || users[0].ID != "u1" {
+		t.Errorf("expected deleted user u1, actual (%+v)", users)
+	}
+
+	if _, err := db.DeleteRows(&softUser{}); err == nil {
+		t.Errorf("delete rows without options expected to fail")
+	}
+	if num := db.MustDeleteRows(&softUser{}, sqlq.Equal("name", "second")); num != 1 {
+		t.Errorf("expected 1 deleted row, actual (%d)", num)
+	}
+	if cnt := db.MustCount(&softUser{}); cnt != 1 {
+		t.Errorf("expected 1 not deleted user, actual (%d)", cnt)
+	}
+
+	db.MustRestore(u1)
+	if !u1.DeletedAt.IsZero() {
+		t.Errorf("deletion time expected to be reset")
+	}
+	if found := db.MustGet(&softUser{ID: "u1"}); !found {
+		t.Errorf("restored user expected to be found")
+	}
+
+	db.MustHardDelete(u1)
+	if cnt := db.MustCount(&softUser{}, sqlq.WithDeleted()); cnt != 2 {
+		t.Errorf("expected 2 users left, actual (%d)", cnt)
+	}
+	num, err := db.HardDeleteRows(&softUser{}, sqlq.All())
+	if err != nil || num != 2 {
+		t.Errorf("expected 2 hard deleted rows, actual (%d), err: %v", num, err)
+	}
+
+	if err := db.Restore(&selectTest{ID: "x"}); err == nil {
+		t.Errorf("restore of model without soft delete expected to fail")
+	}
+}
+
 func TestCount(t *testing.T) {
 	type countUser struct {
 		ID   string
@@ -1236,6 +2116,158 @@ func TestJoin(t *testing.T) {
 
 var ranStrSetAlphaNum = []byte("abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789")
 
+func TestSelectPage(t *testing.T) {
+	type pageItem struct {
+		ID    int
+		Score int
+	}
+	db.MustCreateTable(&pageItem{})
+	// scores: 0,0,1,1,2,2,3,3,4,4
+	for i := 0; i < 10; i++ {
+		db.MustInsert(&pageItem{Score: i / 2})
+	}
+
+	var (
+		items    []pageItem
+		fetched  []int
+		pageOpts = []sqlq.Option{sqlq.Order("score", sqlq.DESC), sqlq.Limit(3)}
+	)
+	page, err := db.SelectPage(&items, pageOpts...)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if page.Prev != "" {
+		t.Errorf("first page shouldn't have previous page cursor")
+	}
+	for page.Next != "" {
+		for _, item := range items {
+			fetched = append(fetched, item.ID)
+		}
+		items = nil
+		page, err = db.SelectPage(&items, append(pageOpts, sqlq.After(page.Next))...)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	for _, item := range items {
+		fetched = append(fetched, item.ID)
+	}
+	if len(fetched) != 10 {
+		t.Fatalf("expected 10 items fetched by pages, actual: (%v)", fetched)
+	}
+	if len(items) != 1 || items[0].Score != 0 {
+		t.Fatalf("last page expected to contain one item with 0 score, actual: (%v)", items)
+	}
+
+	var prevItems []pageItem
+	prevPage, err := db.SelectPage(&prevItems, append(pageOpts, sqlq.Before(page.Prev))...)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(prevItems) != 3 {
+		t.Fatalf("expected 3 items on previous page, actual: (%v)", prevItems)
+	}
+	for i, item := range prevItems {
+		if item.ID != fetched[6+i] {
+			t.Errorf("previous page item (%d) expected to be (%d), actual: (%d)", i, fetched[6+i], item.ID)
+		}
+	}
+	if prevPage.Next == "" || prevPage.Prev == "" {
+		t.Errorf("previous page expected to have both cursors: %+v", prevPage)
+	}
+
+	// rows are appended to existing elements, which aren't a part of the page.
+	appended := []pageItem{{ID: -1}}
+	appendedPage, err := db.SelectPage(&appended, append(pageOpts, sqlq.Before(page.Prev))...)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(appended) != 4 || appended[0].ID != -1 {
+		t.Fatalf("expected page appended to existing item, actual: (%v)", appended)
+	}
+	for i, item := range prevItems {
+		if appended[i+1].ID != item.ID {
+			t.Errorf("appended item (%d) expected to be (%d), actual: (%d)", i, item.ID, appended[i+1].ID)
+		}
+	}
+	if *appendedPage != *prevPage {
+		t.Errorf("expected page (%+v), actual: (%+v)", prevPage, appendedPage)
+	}
+}
+
+func TestIterate(t *testing.T) {
+	type iterSubscription struct {
+		ID     string
+		UserID string
+		URL    string
+	}
+	type iterUser struct {
+		ID            string
+		Name          string
+		Subscriptions []iterSubscription `mw:"join"`
+	}
+	db.MustCreateTable(&iterUser{})
+	db.MustCreateTable(&iterSubscription{})
+
+	users := []*iterUser{
+		{ID: "u1", Name: "user1"},
+		{ID: "u2", Name: "user2"},
+		{ID: "u3", Name: "user3"},
+	}
+	for _, u := range users {
+		db.MustInsert(u)
+	}
+	db.MustInsert(
+		&iterSubscription{ID: "s1", UserID: "u1", URL: "url1"},
+		&iterSubscription{ID: "s2", UserID: "u1", URL: "url2"},
+		&iterSubscription{ID: "s3", UserID: "u3", URL: "url3"},
+	)
+
+	t.Run("iterate", func(t *testing.T) {
+		it, err := db.Iterate(&iterUser{}, sqlq.Order("id", sqlq.ASC))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		defer it.Close()
+
+		var ids []string
+		for it.Next() {
+			u := &iterUser{}
+			if err := it.Scan(u); err != nil {
+				t.Fatalf("scan failed: %v", err)
+			}
+			ids = append(ids, u.ID)
+		}
+		if err := it.Err(); err != nil {
+			t.Fatalf("iteration failed: %v", err)
+		}
+		if len(ids) != 3 || ids[0] != "u1" || ids[2] != "u3" {
+			t.Errorf("expected users (u1,u2,u3), actual: (%v)", ids)
+		}
+	})
+	t.Run("iterate join", func(t *testing.T) {
+		subsNum := make(map[string]int)
+		u := &iterUser{}
+		err := db.ForEach(u, func() error {
+			subsNum[u.ID] = len(u.Subscriptions)
+			return nil
+		},
+			sqlq.Columns("id", "name"),
+			sqlq.Join(&iterSubscription{}, "iter_user.id = iter_subscription.user_id", "url"),
+			sqlq.Order("`iter_user`.`id`", sqlq.ASC),
+		)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(subsNum) != 3 {
+			t.Fatalf("expected 3 users, actual: (%d)", len(subsNum))
+		}
+		if subsNum["u1"] != 2 || subsNum["u2"] != 0 || subsNum["u3"] != 1 {
+			t.Errorf("unexpected number of joined subscriptions: %v", subsNum)
+		}
+	})
+}
+
 func init() {
 	// Seed the random num gen, once for app
 	rand.Seed(time.Now().UTC().UnixNano())
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"reflect"
 	"strconv"
@@ -16,15 +17,10 @@ import (
 )
 
 const (
-	mw_pk_string = "VARCHAR(255) NOT NULL PRIMARY KEY"
-	mw_pk_int    = "INT NOT NULL AUTO_INCREMENT PRIMARY KEY"
-	mw_date_time = "timestamp {nullable}"
-	mw_json      = "JSON"
-	mw_small_int = "SMALLINT {nullable} DEFAULT 0"
-	mw_integer   = "INT {nullable} DEFAULT 0"
-	mw_boolean   = "tinyint(1) {nullable} DEFAULT 0"
-	mw_float     = "DOUBLE {nullable} DEFAULT 0"
-	mw_text      = "VARCHAR(255) {nullable} DEFAULT ''"
+	mw_created = "timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP"
+	mw_updated = "timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP"
+	mw_deleted = "timestamp NULL DEFAULT NULL"
+	mw_json    = "JSON"
 )
 
 func init() {
@@ -44,21 +40,44 @@ type model struct {
 	ReflectType reflect.Type
 
 	// Explicitly store the PK name for where clauses
-	// Note PKName will always be id for now
+	// In case of composite primary key PKName and PKPos describe its first column.
 	PKName string
-	// PKPos is a position of a primary key.
+	// PKPos is a position of a primary key in Fields.
 	PKPos int
+	// PKFields are the primary key fields, more than one in case of composite primary key.
+	PKFields []*field
+	// explicitPK is set if primary key is marked with pk tag, so id field isn't used as primary key.
+	explicitPK bool
+	// CreatedPos and UpdatedPos are positions in Fields of fields tagged as created and updated timestamps, -1 if not set.
+	CreatedPos int
+	UpdatedPos int
+	// SoftDeletePos is a position in Fields of a field tagged as soft_delete, -1 if not set.
+	SoftDeletePos int
+	// VersionPos is a position in Fields of a field tagged as version used for optimistic locking, -1 if not set.
+	VersionPos int
+	// TrackedPos is an index path of embedded Tracked struct enabling dirty tracking, nil if not set.
+	TrackedPos []int
 
 	// used if we don't want to fetch model's fields
 	NoFields bool
 
-	// Joins maps joined table name to joined field position.
-	Joins map[string]int
+	// Joins maps joined table name to index path of joined field.
+	Joins map[string][]int
+
+	// Indexes are declared with index and unique tags, and by Indexes method of the model.
+	Indexes []Index
+	// ForeignKeys are declared with fk tag.
+	ForeignKeys []foreignKey
+	// indexParts are index columns collected while fields are parsed.
+	indexParts []indexPart
 }
 
 func (mod *model) IsIntPK() bool {
+	if mod.IsCompositePK() {
+		return false
+	}
 	switch mod.GetPKKind() {
-	case reflect.Int, reflect.Int32, reflect.Int64:
+	case reflect.Int, reflect.Int32, reflect.Int64, reflect.Uint, reflect.Uint32, reflect.Uint64:
 		return true
 	default:
 		return false
@@ -74,6 +93,53 @@ func (mod *model) GetPKField() *field {
 	return nil
 }
 
+// IsCompositePK checks whether model has primary key of multiple columns.
+func (mod *model) IsCompositePK() bool {
+	return len(mod.PKFields) > 1
+}
+
+// PKColumnsQuoted returns comma separated quoted primary key columns.
+func (mod *model) PKColumnsQuoted() string {
+	cols := make([]string, 0, len(mod.PKFields))
+	for _, f := range mod.PKFields {
+		cols = append(cols, f.MWNameQuoted())
+	}
+	return strings.Join(cols, ", ")
+}
+
+// isPK checks whether field is a part of primary key.
+func (mod *model) isPK(f *field) bool {
+	for _, pk := range mod.PKFields {
+		if pk == f {
+			return true
+		}
+	}
+	return false
+}
+
+// pkCondition returns where condition on primary key columns, like `a` = ? AND `b` = ?,
+// columns are prefixed with table name if withTable is set.
+func (mod *model) pkCondition(withTable bool) string {
+	conds := make([]string, 0, len(mod.PKFields))
+	for _, f := range mod.PKFields {
+		col := f.MWNameQuoted()
+		if withTable {
+			col = "`" + mod.TableName + "`." + col
+		}
+		conds = append(conds, col+" = ?")
+	}
+	return strings.Join(conds, " AND ")
+}
+
+// pkVals returns values of primary key fields of the row, in the order of pkCondition.
+func (mod *model) pkVals(rowModel reflect.Value) []interface{} {
+	vals := make([]interface{}, 0, len(mod.PKFields))
+	for _, f := range mod.PKFields {
+		vals = append(vals, f.value(rowModel, false).Interface())
+	}
+	return vals
+}
+
 func (mod *model) GetPKKind() reflect.Kind {
 	if pkField := mod.GetPKField(); pkField != nil {
 		return pkField.ReflectType.Kind()
@@ -107,26 +173,29 @@ var cachedModelMap modelSyncMap
 
 // In cases like UPDATE we need to get the list of fields sans ID since you can't update PK.
 // Must be exported since the templates call this.
-func (mod *model) GetFieldsNoPK(columns []string) []*field {
-	fields := mod.getFields(columns)
+func (mod *model) GetFieldsNoPK(columns []string) ([]*field, error) {
+	fields, err := mod.getFields(columns)
+	if err != nil {
+		return nil, err
+	}
 	filteredFields := make([]*field, 0, len(fields))
 	for _, f := range fields {
-		if f.MWName == mod.PKName {
+		if mod.isPK(f) {
 			continue
 		}
 		filteredFields = append(filteredFields, f)
 	}
 
-	return filteredFields
+	return filteredFields, nil
 }
 
-func (mod *model) getFields(columns []string) []*field {
+func (mod *model) getFields(columns []string) ([]*field, error) {
 	if len(columns) == 0 {
-		return mod.Fields
+		return mod.Fields, nil
 	}
 	fields := make([]*field, 0, len(columns))
 	for i := range mod.Fields {
-		if i == mod.PKPos {
+		if mod.isPK(mod.Fields[i]) {
 			fields = append(fields, mod.Fields[i])
 			continue
 		}
@@ -143,33 +212,59 @@ func (mod *model) getFields(columns []string) []*field {
 			fields = append(fields, mod.Fields[i])
 		}
 	}
-	if len(columns) != len(fields) { // some column not exists in db
-	ColumnsLoop:
-		for _, col := range columns {
-			for _, f := range fields {
-				if f.MWName == col {
-					continue ColumnsLoop
-				}
+	// primary key columns are always selected, so number of fields doesn't tell whether all columns exist.
+ColumnsLoop:
+	for _, col := range columns {
+		for _, f := range fields {
+			if f.MWName == col {
+				continue ColumnsLoop
 			}
-			panic(fmt.Sprintf("unrecognized column (%s)", col))
 		}
+		return nil, fmt.Errorf("unrecognized column (%s) for table (%s)", col, mod.TableName)
 	}
 
-	return fields
+	return fields, nil
+}
+
+// fieldByPos returns a field by its position in Fields, nil if position isn't set.
+func (mod *model) fieldByPos(pos int) *field {
+	if pos == -1 {
+		return nil
+	}
+	return mod.Fields[pos]
+}
+
+// setTimestamps sets updated timestamp of the row to now, created timestamp is set only for new rows.
+func (mod *model) setTimestamps(rowModel reflect.Value, now time.Time, isNew bool) {
+	if isNew && mod.CreatedPos != -1 {
+		mod.Fields[mod.CreatedPos].value(rowModel, true).Set(reflect.ValueOf(now))
+	}
+	if mod.UpdatedPos != -1 {
+		mod.Fields[mod.UpdatedPos].value(rowModel, true).Set(reflect.ValueOf(now))
+	}
 }
 
 // Get a slice of the vals for interfacing with sql
 func (pm *model) getVals(rowModel reflect.Value, fields []*field) []interface{} {
 	vals := make([]interface{}, 0, len(fields))
 	for _, f := range fields {
-		fieldVal := reflect.Indirect(rowModel).Field(f.FieldPos).Interface()
-		if f.MWType == mw_json {
-			v, err := json.Marshal(fieldVal)
-			if err != nil {
-				if debugEnabled {
-					fmt.Println("marshal failed!", err)
-				}
+		fv := f.value(rowModel, false)
+		if f.Ptr {
+			if fv.IsNil() {
+				vals = append(vals, nil)
+				continue
 			}
+			fv = fv.Elem()
+		}
+		fieldVal := fv.Interface()
+		if f == pm.fieldByPos(pm.SoftDeletePos) && fieldVal.(time.Time).IsZero() {
+			// not deleted row has NULL deletion time, zero time would be stored as 0000-00-00.
+			fieldVal = nil
+		} else if f.Custom {
+			fieldVal = customVal(fv)
+		} else if f.JSON {
+			// marshal errors are ignored, so the value is inserted as null.
+			v, _ := json.Marshal(fieldVal)
 			fieldVal = v
 		}
 		vals = append(vals, fieldVal)
@@ -193,8 +288,9 @@ type field struct {
 	ReflectValue reflect.Value
 	ReflectKind  reflect.Kind
 
-	// FieldPos is a position of a field in our struct.
-	FieldPos int
+	// FieldPos is an index path of a field in our struct, it has several indexes
+	// in case field belongs to embedded struct.
+	FieldPos []int
 
 	mwNameQuoted       string
 	mwNameQuotedSelect string
@@ -202,6 +298,41 @@ type field struct {
 
 	// Nullable specifies whether a field can be NULL.
 	Nullable bool
+	// Custom is set if field type implements sql.Scanner or driver.Valuer,
+	// such values are passed to the driver as is.
+	Custom bool
+	// Ptr is set for pointer fields, which are nullable columns, nil pointer is stored as NULL.
+	// ReflectType and ReflectKind of such field describe pointed type.
+	Ptr bool
+	// JSON is set for fields of structs, maps and slices, which are stored as JSON.
+	JSON bool
+
+	// column is a column definition, MWType is rendered from.
+	column columnDef
+}
+
+// value returns the field of the row. Nil embedded struct pointers on the field path are allocated
+// if alloc is set, otherwise zero value is returned for fields of nil embedded structs.
+func (f *field) value(rowModel reflect.Value, alloc bool) reflect.Value {
+	return valueByIndex(rowModel, f.FieldPos, alloc)
+}
+
+// valueByIndex returns nested struct field by its index path, see field.value.
+func valueByIndex(rowModel reflect.Value, index []int, alloc bool) reflect.Value {
+	v := reflect.Indirect(rowModel)
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				if !alloc {
+					return reflect.Zero(v.Type().Elem().FieldByIndex(index[i:]).Type)
+				}
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v
 }
 
 func (f *field) MWNameQuoted() string {
@@ -219,9 +350,9 @@ func (f *field) MWNameQuoted() string {
 	return f.mwNameQuoted
 }
 
-func (f *field) MWNameQuotedSelect() string {
+func (f *field) MWNameQuotedSelect() (string, error) {
 	if f.mwNameQuotedSelect != "" {
-		return f.mwNameQuotedSelect
+		return f.mwNameQuotedSelect, nil
 	}
 	trimString := func(str string) string {
 		return strings.Replace(strings.Replace(str, ";", "", -1), "`", "", -1)
@@ -236,14 +367,14 @@ func (f *field) MWNameQuotedSelect() string {
 		if len(parts) == 2 {
 			mwName = trimString(parts[0]) + " as " + escapeString(parts[1])
 		} else {
-			panic(fmt.Sprintf("invalid column name (%s)", mwName))
+			return "", fmt.Errorf("invalid column name (%s)", mwName)
 		}
 	} else {
 		mwName = "`" + f.TableName + "`." + escapeString(mwName)
 	}
 
 	f.mwNameQuotedSelect = mwName
-	return f.mwNameQuotedSelect
+	return f.mwNameQuotedSelect, nil
 }
 
 func (f *field) JoinedMWName() string {
@@ -269,9 +400,26 @@ type nullScanner struct {
 var timeType = reflect.TypeOf(time.Time{}).String()
 
 func (scanner *nullScanner) Scan(val interface{}) error {
+	fieldVal := scanner.fieldVal
+	if scanner.field.Ptr {
+		if val == nil {
+			fieldVal.Set(reflect.Zero(fieldVal.Type()))
+			return nil
+		}
+		ptr := reflect.New(scanner.field.ReflectType)
+		fieldVal.Set(ptr)
+		fieldVal = ptr.Elem()
+	}
 	if val == nil {
+		// NULL is scanned as zero value, so values of a struct loaded before are reset.
+		fieldVal.Set(reflect.Zero(fieldVal.Type()))
 		return nil
 	}
+	if scanner.field.Custom {
+		if fs, ok := fieldVal.Addr().Interface().(sql.Scanner); ok {
+			return fs.Scan(val)
+		}
+	}
 
 	var fs sql.Scanner
 	switch scanner.field.ReflectKind {
@@ -284,14 +432,14 @@ func (scanner *nullScanner) Scan(val interface{}) error {
 		fs = &sql.NullFloat64{}
 	case reflect.Bool:
 		fs = &sql.NullBool{}
-	case reflect.Struct, reflect.Array, reflect.Slice:
+	case reflect.Struct, reflect.Array, reflect.Slice, reflect.Map:
 		if scanner.field.ReflectType.String() == timeType {
 			fs = &mysql.NullTime{}
 		} else {
-			fs = &jsonScanner{scanner.fieldVal.Addr().Interface()}
+			fs = &jsonScanner{fieldVal.Addr().Interface()}
 		}
 	default:
-		panic(fmt.Sprintf("cannot scan nullable field of type %s", scanner.field.ReflectKind))
+		return fmt.Errorf("cannot scan nullable field of type %s", scanner.field.ReflectKind)
 	}
 
 	if err := fs.Scan(val); err != nil {
@@ -300,20 +448,20 @@ func (scanner *nullScanner) Scan(val interface{}) error {
 
 	switch sv := fs.(type) {
 	case *sql.NullString:
-		scanner.fieldVal.SetString(sv.String)
+		fieldVal.SetString(sv.String)
 	case *sql.NullInt64:
 		switch scanner.field.ReflectKind {
 		case reflect.Int8, reflect.Int16, reflect.Int, reflect.Int32, reflect.Int64:
-			scanner.fieldVal.SetInt(sv.Int64)
+			fieldVal.SetInt(sv.Int64)
 		case reflect.Uint, reflect.Uint32, reflect.Uint8, reflect.Uint64, reflect.Uint16:
-			scanner.fieldVal.SetUint(uint64(sv.Int64))
+			fieldVal.SetUint(uint64(sv.Int64))
 		}
 	case *sql.NullFloat64:
-		scanner.fieldVal.SetFloat(sv.Float64)
+		fieldVal.SetFloat(sv.Float64)
 	case *sql.NullBool:
-		scanner.fieldVal.SetBool(sv.Bool)
+		fieldVal.SetBool(sv.Bool)
 	case *mysql.NullTime:
-		scanner.fieldVal.Set(reflect.ValueOf(sv.Time))
+		fieldVal.Set(reflect.ValueOf(sv.Time))
 	}
 
 	return nil
@@ -326,7 +474,7 @@ func (fi *field) isPrimaryKey() bool {
 	return false
 }
 
-func (mod *model) setTableName(rowModel reflect.Value) {
+func (mod *model) setTableName(rowModel reflect.Value) error {
 	tableNameMethod := rowModel.MethodByName("TableName")
 
 	if tableNameMethod.IsValid() {
@@ -334,69 +482,205 @@ func (mod *model) setTableName(rowModel reflect.Value) {
 		mod.TableName = vals[0].String()
 	} else {
 		if mod.StructName == "" { // Just in case...
-			panic("Cannot call setTableName until StructName is set")
+			return errors.New("cannot detect table name of anonymous struct, please define TableName method")
 		}
 		mod.TableName = parseName(mod.StructName)
 	}
+
+	return nil
 }
 
+// getPK returns primary key of the row as string, values of composite key are separated with comma,
+// string values of composite key are quoted, so keys containing commas cannot match another key.
+// Empty string is returned if any string key column is empty.
 func (mod *model) getPK(rowModel reflect.Value) string {
 	if mod.PKPos == -1 {
 		panic(fmt.Sprintf("Missing primary key for table (%s)", mod.TableName))
 	}
-	pkType := mw_pk_string
-	for _, f := range mod.Fields {
-		if f.MWName == mod.PKName {
-			pkType = f.MWType
-			break
+	parts := make([]string, 0, len(mod.PKFields))
+	for _, f := range mod.PKFields {
+		val := f.value(rowModel, false)
+		var part string
+		switch val.Kind() {
+		case reflect.Int, reflect.Int32, reflect.Int64:
+			part = strconv.FormatInt(val.Int(), 10)
+		case reflect.Uint, reflect.Uint32, reflect.Uint64:
+			part = strconv.FormatUint(val.Uint(), 10)
+		default:
+			part = val.String()
+			if part != "" && mod.IsCompositePK() {
+				part = strconv.Quote(part)
+			}
+		}
+		if part == "" {
+			return ""
 		}
+		parts = append(parts, part)
 	}
+	return strings.Join(parts, ",")
+}
+
+// ValidateModel checks whether struct can be used as a model: the argument is a struct pointer,
+// mw tags are valid, field types are supported and primary key is set.
+// It may be called at startup to find out model errors before queries are executed.
+func ValidateModel(structPtr interface{}) error {
+	_, err := parseModel(structPtr, true)
+	return err
+}
 
-	if pkType == mw_pk_int {
-		return strconv.FormatInt(reflect.Indirect(rowModel).Field(mod.PKPos).Int(), 10)
+// mustParseModel is the same as parseModel, but panics in case of an error.
+func mustParseModel(mm interface{}, requirePK bool) *model {
+	mod, err := parseModel(mm, requirePK)
+	if err != nil {
+		panic(err)
 	}
-	return reflect.Indirect(rowModel).Field(mod.PKPos).String()
+	return mod
 }
 
-func parseModel(mm interface{}, requirePK bool) *model {
+func parseModel(mm interface{}, requirePK bool) (*model, error) {
 	modType := reflect.TypeOf(mm)
+	if modType == nil {
+		return nil, errors.New("please pass a struct pointer as model, got nil")
+	}
 	typeName := modType.String()
 	if mod, ok := cachedModelMap.Get(typeName); ok {
-		return mod
+		if requirePK && mod.PKName == "" {
+			return nil, fmt.Errorf("missing primary key for table (%s)", mod.TableName)
+		}
+		return mod, nil
 	}
 
 	mod := &model{
-		Struct:      mm,
-		ReflectType: modType,
+		Struct:        mm,
+		ReflectType:   modType,
+		PKPos:         -1,
+		CreatedPos:    -1,
+		UpdatedPos:    -1,
+		SoftDeletePos: -1,
+		VersionPos:    -1,
 	}
 	modKind := modType.Kind()
 	rowModel := reflect.ValueOf(mm)
 
-	if modKind != reflect.Ptr || rowModel.Elem().Kind() != reflect.Struct {
-		panic("Please pass a struct pointer to parseModel")
+	if modKind != reflect.Ptr || rowModel.IsNil() || rowModel.Elem().Kind() != reflect.Struct {
+		return nil, fmt.Errorf("please pass a struct pointer as model, got (%T)", mm)
 	}
 	elem := rowModel.Elem()
 	elemType := elem.Type()
 	mod.StructName = elemType.Name()
 
-	mod.setTableName(rowModel)
+	if err := mod.setTableName(rowModel); err != nil {
+		return nil, err
+	}
 
-	fieldLen := elem.NumField()
-	mod.Fields = make([]*field, 0, fieldLen)
-	for i := 0; i < fieldLen; i++ {
-		fieldName := elemType.Field(i).Name
+	mod.Fields = make([]*field, 0, elem.NumField())
+	mod.explicitPK = hasPKTag(elemType)
+	if err := mod.parseFields(elemType, nil); err != nil {
+		return nil, err
+	}
+	mod.renderPK()
+	if err := mod.buildIndexes(mm); err != nil {
+		return nil, fmt.Errorf("model (%s): %v", mod.StructName, err)
+	}
+	if requirePK && mod.PKName == "" {
+		return nil, fmt.Errorf("missing primary key for table (%s)", mod.TableName)
+	}
+	cachedModelMap.Set(typeName, mod)
+
+	return mod, nil
+}
+
+// splitTagOptions splits mw tag into comma separated options. Commas inside parentheses, single quotes
+// and between decimal precision and scale are kept, e.g. "type:DECIMAL(12,2),comment:'a, b'" or "decimal:12,2".
+func splitTagOptions(tag string) []string {
+	var (
+		opts    []string
+		depth   int
+		start   int
+		inQuote bool
+	)
+	for i, r := range tag {
+		switch r {
+		case '\'':
+			inQuote = !inQuote
+		case '(':
+			depth++
+		case ')':
+			if depth > 0 {
+				depth--
+			}
+		case ',':
+			if depth != 0 || inQuote {
+				continue
+			}
+			if opt := strings.TrimSpace(tag[start:i]); strings.HasPrefix(opt, "decimal:") && !strings.Contains(opt, ",") {
+				continue
+			}
+			opts = append(opts, tag[start:i])
+			start = i + 1
+		}
+	}
+	return append(opts, tag[start:])
+}
+
+// hasPKTag checks whether any field of the struct, including fields of embedded structs, has pk tag.
+func hasPKTag(structType reflect.Type) bool {
+	for i := 0; i < structType.NumField(); i++ {
+		sf := structType.Field(i)
+		if embedded := embeddedStruct(sf); embedded != nil && hasPKTag(embedded) {
+			return true
+		}
+		for _, opt := range splitTagOptions(sf.Tag.Get("mw")) {
+			if strings.TrimSpace(opt) == "pk" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// embeddedStruct returns type of anonymous struct field, which columns are flattened into the model,
+// nil if field isn't such struct.
+func embeddedStruct(sf reflect.StructField) reflect.Type {
+	if !sf.Anonymous {
+		return nil
+	}
+	switch strings.TrimSpace(sf.Tag.Get("mw")) {
+	case "-", "join":
+		return nil
+	}
+	t := sf.Type
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct || t == trackedType || t.String() == timeType || isCustomType(t) {
+		return nil
+	}
+	return t
+}
+
+// parseFields parses fields of the model struct, index is an index path of embedded struct being parsed.
+func (mod *model) parseFields(structType reflect.Type, index []int) error {
+	for i := 0; i < structType.NumField(); i++ {
+		sf := structType.Field(i)
+		fieldIndex := append(append(make([]int, 0, len(index)+1), index...), i)
+		fieldName := sf.Name
 
 		// Get the mw struct tag for this field
-		tagValue := strings.TrimSpace(elemType.Field(i).Tag.Get("mw"))
+		tagValue := strings.TrimSpace(sf.Tag.Get("mw"))
 		if tagValue == "-" {
 			continue
 		}
-		fieldType := elemType.Field(i).Type
+		fieldType := sf.Type
 		fieldKind := fieldType.Kind()
+		if sf.Anonymous && fieldType == trackedType {
+			mod.TrackedPos = fieldIndex
+			continue
+		}
 
 		if tagValue == "join" {
 			if mod.Joins == nil {
-				mod.Joins = make(map[string]int)
+				mod.Joins = make(map[string][]int)
 			}
 			var joinType string
 			elType := fieldType
@@ -408,7 +692,7 @@ func parseModel(mm interface{}, requirePK bool) *model {
 			} else {
 				joinType = elType.Name()
 			}
-			mod.Joins[joinType] = i
+			mod.Joins[joinType] = fieldIndex
 			continue
 		}
 		// reserved field name
@@ -418,116 +702,200 @@ func parseModel(mm interface{}, requirePK bool) *model {
 			}
 			continue
 		}
+		if embedded := embeddedStruct(sf); embedded != nil {
+			// pointer to unexported struct cannot be allocated by reflection.
+			if fieldKind == reflect.Ptr && sf.PkgPath != "" {
+				return fmt.Errorf("model (%s) field (%s): embedded pointer to unexported struct is not supported", mod.StructName, fieldName)
+			}
+			if err := mod.parseFields(embedded, fieldIndex); err != nil {
+				return err
+			}
+			continue
+		}
 
 		var mwName string
-		if tagName := strings.TrimSpace(elemType.Field(i).Tag.Get("sql_name")); tagName != "" {
+		if tagName := strings.TrimSpace(sf.Tag.Get("sql_name")); tagName != "" {
 			mwName = tagName
 		} else {
 			mwName = parseName(fieldName)
 		}
+		for _, f := range mod.Fields {
+			if f.MWName == mwName {
+				return fmt.Errorf("model (%s) field (%s): duplicate column (%s)", mod.StructName, fieldName, mwName)
+			}
+		}
 
-		// Support PK and - struct tags for now
+		var isPtr bool
+		if fieldKind == reflect.Ptr {
+			isPtr = true
+			fieldType = fieldType.Elem()
+			fieldKind = fieldType.Kind()
+			if fieldKind == reflect.Ptr {
+				return fmt.Errorf("model (%s) field (%s): unsupported pointer to pointer type", mod.StructName, fieldName)
+			}
+		}
+
+		tagOpts := splitTagOptions(tagValue)
 		newField := &field{
 			TableName:   mod.TableName,
 			GoName:      fieldName,
 			MWName:      mwName,
 			ReflectKind: fieldKind,
 			ReflectType: fieldType,
-			FieldPos:    i,
+			FieldPos:    fieldIndex,
+			Custom:      isCustomType(fieldType),
+			Ptr:         isPtr,
 		}
-		if tagValue == "nullable" {
+		if _, ok := nullTypes[fieldType]; ok || isPtr {
 			newField.Nullable = true
 		}
+		for _, opt := range tagOpts {
+			if strings.TrimSpace(opt) == "nullable" {
+				newField.Nullable = true
+			}
+		}
 
-		newField.setMWType(mod, tagValue)
+		if err := newField.setMWType(mod, tagOpts); err != nil {
+			return fmt.Errorf("model (%s) field (%s): %v", mod.StructName, fieldName, err)
+		}
+		if err := mod.setFieldIndexes(newField, tagOpts); err != nil {
+			return fmt.Errorf("model (%s) field (%s): %v", mod.StructName, fieldName, err)
+		}
+		pos := len(mod.Fields)
 		if newField.MWName == mod.PKName {
-			mod.PKPos = i
+			mod.PKPos = pos
+		}
+		switch newField.MWType {
+		case mw_created:
+			mod.CreatedPos = pos
+		case mw_updated:
+			mod.UpdatedPos = pos
 		}
 
 		mod.Fields = append(mod.Fields, newField)
 	}
-	// TODO we really need to do more inspection of the model to make sure there isn't
-	// more than one PK and/or warn about ID field in addition to PK
-	if requirePK && mod.PKName == "" {
-		panic(fmt.Sprintf("Missing primary key for table (%s)", mod.TableName))
-	}
-	cachedModelMap.Set(typeName, mod)
 
-	return mod
+	return nil
 }
 
-func (fi *field) setMWType(mod *model, tagVal string) {
-
-	switch tagVal {
-	case "pk":
-		mod.PKName = fi.MWName
-		switch fi.ReflectType.Kind() {
-		case reflect.Int, reflect.Int32, reflect.Int64:
-			fi.MWType = mw_pk_int
-			return
-		case reflect.String:
-			fi.MWType = mw_pk_string
-			return
-		default:
-			panic(fmt.Sprintf("unsupported type (%s) for primary key", fi.ReflectType.Kind()))
+// setMWType sets mysql column type of a field by its type and comma separated mw tag options.
+func (fi *field) setMWType(mod *model, tagOpts []string) error {
+	if fi.Ptr {
+		for _, tagVal := range tagOpts {
+			switch tagVal = strings.TrimSpace(tagVal); tagVal {
+			case "pk", "created", "updated", "soft_delete", "version":
+				return fmt.Errorf("%s field cannot be a pointer", tagVal)
+			}
 		}
-	case "", "nullable": // Do nothing special
-	default:
-		panic("Invalid mw tag " + tagVal)
 	}
-
-	if fi.MWName == "id" && mod.PKName == "" {
-		switch fi.ReflectType.Kind() {
-		case reflect.Int, reflect.Int32, reflect.Int64:
-			fi.MWType = mw_pk_int
-		case reflect.String:
-			fi.MWType = mw_pk_string
+	var (
+		timestampType string
+		isPK          bool
+		columnOpts    []string
+		indexOpts     int
+	)
+	for _, tagVal := range tagOpts {
+		tagVal = strings.TrimSpace(tagVal)
+		if isColumnOption(tagVal) {
+			columnOpts = append(columnOpts, tagVal)
+			continue
+		}
+		if isIndexOption(tagVal) {
+			// indexes are set once field type is set.
+			indexOpts++
+			continue
+		}
+		switch tagVal {
+		case "pk":
+			isPK = true
+		case "created", "updated":
+			if fi.ReflectType.String() != timeType {
+				return fmt.Errorf("%s tag requires time.Time field, got (%s)", tagVal, fi.ReflectType)
+			}
+			if fi.Nullable || timestampType != "" {
+				return fmt.Errorf("%s tag cannot be combined with (%s)", tagVal, strings.Join(tagOpts, ","))
+			}
+			if tagVal == "created" {
+				timestampType = mw_created
+			} else {
+				timestampType = mw_updated
+			}
+		case "soft_delete":
+			if fi.ReflectType.String() != timeType {
+				return fmt.Errorf("%s tag requires time.Time field, got (%s)", tagVal, fi.ReflectType)
+			}
+			if timestampType != "" {
+				return fmt.Errorf("%s tag cannot be combined with (%s)", tagVal, strings.Join(tagOpts, ","))
+			}
+			if mod.SoftDeletePos != -1 {
+				return errors.New("only one soft delete field allowed per model")
+			}
+			// field is appended to Fields after its type is set.
+			mod.SoftDeletePos = len(mod.Fields)
+			fi.Nullable = true
+			timestampType = mw_deleted
+		case "version":
+			switch fi.ReflectKind {
+			case reflect.Int, reflect.Int32, reflect.Int64:
+			default:
+				return fmt.Errorf("unsupported type (%s) for version", fi.ReflectKind)
+			}
+			if len(tagOpts) > 1 {
+				return errors.New("version tag cannot be combined with other options")
+			}
+			if mod.VersionPos != -1 {
+				return errors.New("only one version field allowed per model")
+			}
+			col, err := fi.baseColumn()
+			if err != nil {
+				return err
+			}
+			mod.VersionPos = len(mod.Fields)
+			fi.column = col
+			fi.MWType = col.render(true, "")
+			return nil
+		case "", "nullable": // Do nothing special
 		default:
-			panic(fmt.Sprintf("unsupported type (%s) for primary key", fi.ReflectType.Kind()))
+			return fmt.Errorf("invalid mw tag (%s)", tagVal)
 		}
-		mod.PKName = "id"
-	} else if fi.ReflectType.String() == timeType {
-		// Slight hack to see if this is a time object, otherwise we'll use the long below switch
-		fi.MWType = mw_date_time
-	} else {
-		fi.MWType = getMWBaseType(fi.ReflectKind)
-	}
-	if strings.Contains(fi.MWType, "{nullable}") {
-		var nullStr string
-		if !fi.Nullable {
-			nullStr = "NOT NULL"
-		}
-		fi.MWType = strings.Replace(fi.MWType, "{nullable}", nullStr, 1)
-	}
-}
-
-func getMWBaseType(goType reflect.Kind) string {
-	switch goType {
-	// All supported jsonb types. TODO test ptr, might have to dive
-	case reflect.Array, reflect.Slice:
-		return mw_json
-
-	case reflect.Map, reflect.Struct:
-		return mw_json
-	// Small int
-	case reflect.Int8, reflect.Int16:
-		return mw_small_int
-	// Note postgres does not support uint, so we'll just use int
-	case reflect.Int, reflect.Int32, reflect.Uint, reflect.Uint32, reflect.Uint8,
-		reflect.Uint16:
-		return mw_integer
-	case reflect.Int64, reflect.Uint64:
-		return mw_integer
-	case reflect.Float32:
-		return mw_float
-	case reflect.Float64:
-		return mw_float
-	case reflect.String:
-		return mw_text
-	case reflect.Bool:
-		return mw_boolean
 	}
-	panic(fmt.Sprintf("Unsupported type %d (%s)", goType, goType))
+	if isPK {
+		if len(tagOpts) != len(columnOpts)+indexOpts+1 {
+			return errors.New("pk tag cannot be combined with other options")
+		}
+		return fi.setPK(mod, columnOpts)
+	}
+	if timestampType != "" {
+		if len(columnOpts) != 0 {
+			return fmt.Errorf("column options cannot be used with (%s) field", strings.Join(tagOpts, ","))
+		}
+		if (timestampType == mw_created && mod.CreatedPos != -1) || (timestampType == mw_updated && mod.UpdatedPos != -1) {
+			return errors.New("only one created and one updated field allowed per model")
+		}
+		fi.MWType = timestampType
+		return nil
+	}
+
+	if fi.MWName == "id" && !mod.explicitPK {
+		return fi.setPK(mod, columnOpts)
+	}
+	return fi.setColumn(columnOpts)
+}
+
+// setPK makes field a primary key, or a part of composite primary key.
+func (fi *field) setPK(mod *model, columnOpts []string) error {
+	if fi.Ptr {
+		return errors.New("primary key cannot be a pointer")
+	}
+	if err := fi.setPKColumn(columnOpts); err != nil {
+		return err
+	}
+	if mod.PKName == "" {
+		mod.PKName = fi.MWName
+	}
+	mod.PKFields = append(mod.PKFields, fi)
+
+	return nil
 }
 
 func parseName(name string) string {
@@ -6,6 +6,7 @@ import (
 	"regexp"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/cliqueinc/mysql-wear/util"
 )
@@ -29,9 +30,9 @@ func TestParseModelSimple(t *testing.T) {
 		util.RandomString(40),
 	}
 
-	model := parseModel(addr, true)
-	if model == nil {
-		t.Fatalf("parseModel failed, got nil model back")
+	model, err := parseModel(addr, true)
+	if err != nil {
+		t.Fatalf("parseModel failed: %v", err)
 	}
 	if model.StructName != "simpleAddress" || model.ReflectType.Kind() != reflect.Ptr {
 		t.Errorf("parseModel failed, expected name simpleAddress type ptr, %v", model)
@@ -69,34 +70,221 @@ func TestParseModelSimple(t *testing.T) {
 	}
 }
 
-func assertPanicParseModel(t *testing.T, badThing interface{}) {
-	defer func() {
-		if r := recover(); r == nil {
-			t.Errorf("assertPanicParseModel should have panicked")
-		}
-	}()
-	parseModel(badThing, true)
+func assertErrorParseModel(t *testing.T, badThing interface{}) {
+	if _, err := parseModel(badThing, true); err == nil {
+		t.Errorf("parseModel of (%T) should have failed", badThing)
+	}
+	if err := ValidateModel(badThing); err == nil {
+		t.Errorf("ValidateModel of (%T) should have failed", badThing)
+	}
 }
 
-// This test should panic on the current not supported struct pointer field
-func TestParseModelPanicPtrField(t *testing.T) {
+func TestParseModelPtrField(t *testing.T) {
 	type ptrAddress struct {
+		ID     int
 		Street string
 		State  *string
+		Zip    *int64
 		City   string
 	}
-	assertPanicParseModel(t, &ptrAddress{})
+	mod, err := parseModel(&ptrAddress{}, true)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	state := mod.fieldByPos(2)
+	if !state.Ptr || !state.Nullable || state.ReflectKind != reflect.String {
+		t.Errorf("expected nullable string pointer field, got (%+v)", state)
+	}
+	if zip := mod.fieldByPos(3); zip.MWType != "BIGINT DEFAULT 0" {
+		t.Errorf("expected nullable int column, got (%s)", zip.MWType)
+	}
+
+	type ptrPK struct {
+		ID *string
+	}
+	type ptrCreated struct {
+		ID      int
+		Created *time.Time `mw:"created"`
+	}
+	type ptrToPtr struct {
+		ID   int
+		Name **string
+	}
+	for _, m := range []interface{}{&ptrPK{}, &ptrCreated{}, &ptrToPtr{}} {
+		assertErrorParseModel(t, m)
+	}
+}
+
+func TestParseModelEmbedded(t *testing.T) {
+	type Base struct {
+		Tracked
+		ID      string
+		Created time.Time `mw:"created"`
+	}
+	type Address struct {
+		City string
+	}
+	type embeddedUser struct {
+		*Base
+		Address
+		Name string
+	}
+	mod, err := parseModel(&embeddedUser{}, true)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var columns []string
+	for _, f := range mod.Fields {
+		columns = append(columns, f.MWName)
+	}
+	if strings.Join(columns, ",") != "id,created,city,name" {
+		t.Errorf("expected embedded fields flattened, got (%v)", columns)
+	}
+	if mod.PKName != "id" || mod.fieldByPos(mod.CreatedPos).MWName != "created" || len(mod.TrackedPos) != 2 {
+		t.Errorf("expected primary key, created and tracked positions in embedded struct (%+v)", mod)
+	}
+
+	u := &embeddedUser{Name: "user"}
+	if pk := mod.getPK(reflect.ValueOf(u)); pk != "" {
+		t.Errorf("expected empty primary key of nil embedded struct, got (%s)", pk)
+	}
+	mod.Fields[0].value(reflect.ValueOf(u), true).SetString("id1")
+	if u.Base == nil || u.ID != "id1" {
+		t.Errorf("expected embedded struct allocated (%+v)", u)
+	}
+
+	type duplicateColumn struct {
+		Address
+		City string
+	}
+	assertErrorParseModel(t, &duplicateColumn{})
+}
+
+func TestGetPKComposite(t *testing.T) {
+	type groupMember struct {
+		GroupID string `mw:"pk"`
+		UserID  string `mw:"pk"`
+		Role    int    `mw:"pk"`
+	}
+	mod, err := parseModel(&groupMember{}, true)
+	if err != nil {
+		t.Fatalf("fail parse model: %v", err)
+	}
+	first := mod.getPK(reflect.ValueOf(&groupMember{GroupID: "a,b", UserID: "c", Role: 1}))
+	second := mod.getPK(reflect.ValueOf(&groupMember{GroupID: "a", UserID: "b,c", Role: 1}))
+	if first == second {
+		t.Errorf("different composite keys expected to differ, got (%s)", first)
+	}
+	if pk := mod.getPK(reflect.ValueOf(&groupMember{GroupID: "a", Role: 1})); pk != "" {
+		t.Errorf("expected empty primary key, got (%s)", pk)
+	}
 }
 
-// This test should panic on the current not supported struct pointer field
-func TestParseModelPanicNonStruct(t *testing.T) {
+// This test should fail on the current not supported struct pointer field
+func TestParseModelErrorNonStruct(t *testing.T) {
 	type ptrAddress struct {
 		Street string
 		State  *string
 		City   string
 	}
-	assertPanicParseModel(t, ptrAddress{})
-	assertPanicParseModel(t, 42)
+	assertErrorParseModel(t, ptrAddress{})
+	assertErrorParseModel(t, 42)
+	assertErrorParseModel(t, nil)
+	assertErrorParseModel(t, (*ptrAddress)(nil))
+}
+
+func TestValidateModel(t *testing.T) {
+	type validModel struct {
+		ID   int
+		Name string `mw:"nullable"`
+	}
+	type badTag struct {
+		ID   int
+		Name string `mw:"unknown"`
+	}
+	type badPK struct {
+		ID float64
+	}
+	type noPK struct {
+		Name string
+	}
+	type badCreated struct {
+		ID      int
+		Created string `mw:"created"`
+	}
+	type twoUpdated struct {
+		ID       int
+		Updated  time.Time `mw:"updated"`
+		Modified time.Time `mw:"updated"`
+	}
+	type nullableUpdated struct {
+		ID      int
+		Updated time.Time `mw:"updated,nullable"`
+	}
+
+	if err := ValidateModel(&validModel{}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	type emptyType struct {
+		ID   int
+		Name string `mw:"type:"`
+	}
+	type typedCreated struct {
+		ID      int
+		Created time.Time `mw:"created,type:DATETIME"`
+	}
+	type sizedInt struct {
+		ID    int
+		Count int `mw:"size:10"`
+	}
+	type unsignedString struct {
+		ID   int
+		Name string `mw:"unsigned"`
+	}
+	type badDecimal struct {
+		ID    int
+		Price float64 `mw:"decimal:12"`
+	}
+	type pkDefault struct {
+		ID string `mw:"pk,default:'x'"`
+	}
+	type badFK struct {
+		ID     int
+		UserID int `mw:"fk:user"`
+	}
+	type badFKAction struct {
+		ID     int
+		UserID int `mw:"fk:user.id,on_delete:drop"`
+	}
+	type orderWithoutIndex struct {
+		ID     int
+		UserID int `mw:"order:1"`
+	}
+	type mixedIndex struct {
+		ID    int
+		Name  string `mw:"index:idx_name"`
+		Email string `mw:"unique:idx_name"`
+	}
+	badModels := []interface{}{
+		&badTag{}, &badPK{}, &noPK{}, &badCreated{}, &twoUpdated{}, &nullableUpdated{}, &emptyType{}, &typedCreated{},
+		&sizedInt{}, &unsignedString{}, &badDecimal{}, &pkDefault{}, &badFK{}, &badFKAction{}, &orderWithoutIndex{},
+		&mixedIndex{},
+	}
+	for _, m := range badModels {
+		assertErrorParseModel(t, m)
+	}
+
+	// model without primary key may be parsed when it isn't required,
+	// but it still cannot be used in operations which require it.
+	if _, err := parseModel(&noPK{}, false); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := ValidateModel(&noPK{}); err == nil {
+		t.Errorf("model without primary key should be invalid")
+	}
+	if err := db.Select(&[]badTag{}); err == nil {
+		t.Errorf("select of invalid model should fail")
+	}
 }
 
 type tableMeth struct {
@@ -0,0 +1,489 @@
+package mwear
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// regExpIntWidth matches display width of integer column types, which is omitted by mysql 8.
+var regExpIntWidth = regexp.MustCompile(`^(tinyint|smallint|mediumint|int|bigint)\(\d+\)`)
+
+// SchemaDiff holds statements migrating live database schema to the schema of models.
+type SchemaDiff struct {
+	// Up statements migrate database to models schema, Down statements revert them.
+	Up   []string
+	Down []string
+}
+
+// Empty checks whether database schema matches models.
+func (d *SchemaDiff) Empty() bool {
+	return len(d.Up) == 0
+}
+
+// UpSQL returns up migration sql.
+func (d *SchemaDiff) UpSQL() string {
+	return joinStatements(d.Up)
+}
+
+// DownSQL returns down migration sql.
+func (d *SchemaDiff) DownSQL() string {
+	return joinStatements(d.Down)
+}
+
+// WriteMigration writes up and down migration files into migration path, returns new migration version.
+func (d *SchemaDiff) WriteMigration() (string, error) {
+	if d.Empty() {
+		return "", errors.New("schema is up to date, nothing to migrate")
+	}
+	version := time.Now().UTC().Format(VersionTimeFormat)
+	if err := writeMigrationFiles(version, d.UpSQL(), d.DownSQL()); err != nil {
+		return "", err
+	}
+	return version, nil
+}
+
+// add adds a change with its rollback, rollback statements are executed in reverse order.
+func (d *SchemaDiff) add(up, down string) {
+	d.Up = append(d.Up, up)
+	d.Down = append([]string{down}, d.Down...)
+}
+
+func joinStatements(stmts []string) string {
+	if len(stmts) == 0 {
+		return ""
+	}
+	return strings.Join(stmts, ";\n") + ";\n"
+}
+
+// DiffSchema compares tables of models with live tables of the database, read from INFORMATION_SCHEMA,
+// and returns statements which add, modify and drop columns, indexes and foreign keys so the tables match models.
+// Missing tables are created. Primary key changes aren't detected.
+func DiffSchema(db *DB, structPtrs ...interface{}) (*SchemaDiff, error) {
+	return DiffSchemaContext(context.Background(), db, structPtrs...)
+}
+
+// DiffSchemaContext is the same as DiffSchema, but uses context.
+func DiffSchemaContext(ctx context.Context, db *DB, structPtrs ...interface{}) (*SchemaDiff, error) {
+	diff := &SchemaDiff{}
+	for _, structPtr := range structPtrs {
+		mod, err := parseModel(structPtr, true)
+		if err != nil {
+			return nil, err
+		}
+		table, err := db.tableInfo(ctx, mod.TableName)
+		if err != nil {
+			return nil, fmt.Errorf("fail get table (%s) info: %w", mod.TableName, err)
+		}
+		if table == nil {
+			schema, err := renderTemplate(mod, createTableTemplate)
+			if err != nil {
+				return nil, err
+			}
+			schema = strings.TrimSuffix(strings.TrimSpace(schema[strings.Index(schema, "CREATE TABLE"):]), ";")
+			diff.add(schema, "DROP TABLE `"+mod.TableName+"`")
+			continue
+		}
+		mod.diffTable(table, diff)
+	}
+	return diff, nil
+}
+
+// tableInfo is a live table definition.
+type tableInfo struct {
+	Columns     []liveColumn
+	Indexes     []Index
+	ForeignKeys []foreignKey
+	// MultiColumnFKs are names of multi column foreign keys, they cannot be declared by models, so they are kept as is.
+	MultiColumnFKs map[string]bool
+}
+
+// liveColumn is a column definition read from INFORMATION_SCHEMA.COLUMNS.
+type liveColumn struct {
+	Name     string
+	Type     string
+	Nullable bool
+	// Default is nil if column has no default value.
+	Default *string
+	Extra   string
+	Comment string
+	Charset string
+	// PK is set for primary key columns.
+	PK bool
+}
+
+// definition returns column definition used to restore the column.
+func (c liveColumn) definition() string {
+	parts := []string{c.Type}
+	if c.Nullable {
+		parts = append(parts, "NULL")
+	} else {
+		parts = append(parts, "NOT NULL")
+	}
+	extra := strings.TrimSpace(strings.Replace(strings.ToLower(c.Extra), "default_generated", "", 1))
+	if c.Default != nil {
+		parts = append(parts, "DEFAULT "+c.defaultSQL())
+	}
+	if extra != "" {
+		parts = append(parts, extra)
+	}
+	if c.Comment != "" {
+		parts = append(parts, "COMMENT '"+strings.Replace(c.Comment, "'", "''", -1)+"'")
+	}
+	return strings.Join(parts, " ")
+}
+
+// defaultSQL returns raw sql default value of the column, which has default.
+func (c liveColumn) defaultSQL() string {
+	def := *c.Default
+	switch {
+	case isCurrentTimestamp(def), isNumericType(c.Type) && isNumber(def):
+		return def
+	case strings.Contains(strings.ToLower(c.Extra), "default_generated"):
+		// expression default.
+		return "(" + def + ")"
+	}
+	return "'" + strings.Replace(strings.Trim(def, "'"), "'", "''", -1) + "'"
+}
+
+// tableInfo reads live table definition, returns nil if table doesn't exist.
+func (db *DB) tableInfo(ctx context.Context, tableName string) (*tableInfo, error) {
+	rows, err := db.query(ctx, OpSelect, tableName, `SELECT COLUMN_NAME, COLUMN_TYPE, IS_NULLABLE, COLUMN_DEFAULT, EXTRA,
+		COLUMN_COMMENT, COALESCE(CHARACTER_SET_NAME, ''), COLUMN_KEY FROM INFORMATION_SCHEMA.COLUMNS
+		WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ? ORDER BY ORDINAL_POSITION`, tableName)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	table := &tableInfo{}
+	for rows.Next() {
+		var (
+			col           liveColumn
+			nullable, key string
+		)
+		err := rows.Scan(&col.Name, &col.Type, &nullable, &col.Default, &col.Extra, &col.Comment, &col.Charset, &key)
+		if err != nil {
+			return nil, err
+		}
+		col.Nullable, col.PK = nullable == "YES", key == "PRI"
+		table.Columns = append(table.Columns, col)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(table.Columns) == 0 {
+		return nil, nil
+	}
+
+	idxRows, err := db.query(ctx, OpSelect, tableName, `SELECT INDEX_NAME, NON_UNIQUE, COLUMN_NAME, SUB_PART
+		FROM INFORMATION_SCHEMA.STATISTICS WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ? AND INDEX_NAME != 'PRIMARY'
+		ORDER BY INDEX_NAME, SEQ_IN_INDEX`, tableName)
+	if err != nil {
+		return nil, err
+	}
+	defer idxRows.Close()
+
+	for idxRows.Next() {
+		var (
+			name, column string
+			nonUnique    int
+			subPart      *int
+		)
+		if err := idxRows.Scan(&name, &nonUnique, &column, &subPart); err != nil {
+			return nil, err
+		}
+		if subPart != nil {
+			column += "(" + strconv.Itoa(*subPart) + ")"
+		}
+		if n := len(table.Indexes); n != 0 && table.Indexes[n-1].Name == name {
+			table.Indexes[n-1].Columns = append(table.Indexes[n-1].Columns, column)
+			continue
+		}
+		table.Indexes = append(table.Indexes, Index{Name: name, Unique: nonUnique == 0, Columns: []string{column}})
+	}
+	if err := idxRows.Err(); err != nil {
+		return nil, err
+	}
+
+	fkRows, err := db.query(ctx, OpSelect, tableName, `SELECT k.CONSTRAINT_NAME, k.COLUMN_NAME, k.REFERENCED_TABLE_NAME,
+		k.REFERENCED_COLUMN_NAME, r.DELETE_RULE, r.UPDATE_RULE FROM INFORMATION_SCHEMA.KEY_COLUMN_USAGE k
+		JOIN INFORMATION_SCHEMA.REFERENTIAL_CONSTRAINTS r
+		ON r.CONSTRAINT_SCHEMA = k.CONSTRAINT_SCHEMA AND r.CONSTRAINT_NAME = k.CONSTRAINT_NAME
+		WHERE k.TABLE_SCHEMA = DATABASE() AND k.TABLE_NAME = ? AND k.REFERENCED_TABLE_NAME IS NOT NULL
+		ORDER BY k.CONSTRAINT_NAME, k.ORDINAL_POSITION`, tableName)
+	if err != nil {
+		return nil, err
+	}
+	defer fkRows.Close()
+
+	table.MultiColumnFKs = make(map[string]bool)
+	for fkRows.Next() {
+		var fk foreignKey
+		if err := fkRows.Scan(&fk.Name, &fk.Column, &fk.RefTable, &fk.RefColumn, &fk.OnDelete, &fk.OnUpdate); err != nil {
+			return nil, err
+		}
+		if n := len(table.ForeignKeys); n != 0 && table.ForeignKeys[n-1].Name == fk.Name {
+			table.MultiColumnFKs[fk.Name] = true
+			continue
+		}
+		table.ForeignKeys = append(table.ForeignKeys, fk)
+	}
+	if err := fkRows.Err(); err != nil {
+		return nil, err
+	}
+	fks := table.ForeignKeys[:0]
+	for _, fk := range table.ForeignKeys {
+		if !table.MultiColumnFKs[fk.Name] {
+			fks = append(fks, fk)
+		}
+	}
+	table.ForeignKeys = fks
+
+	return table, nil
+}
+
+// diffTable adds statements migrating live table to the model to the diff.
+func (mod *model) diffTable(table *tableInfo, diff *SchemaDiff) {
+	alter := "ALTER TABLE `" + mod.TableName + "` "
+
+	modelFKs := make(map[string]foreignKey, len(mod.ForeignKeys))
+	for _, fk := range mod.ForeignKeys {
+		modelFKs[fk.Name] = fk
+	}
+	liveFKs := make(map[string]foreignKey, len(table.ForeignKeys))
+	for _, fk := range table.ForeignKeys {
+		liveFKs[fk.Name] = fk
+		if modelFK, ok := modelFKs[fk.Name]; !ok || !fk.equal(modelFK) {
+			diff.add(alter+"DROP FOREIGN KEY `"+fk.Name+"`", alter+"ADD "+fk.Definition())
+		}
+	}
+
+	modelIndexes := make(map[string]Index, len(mod.Indexes))
+	for _, idx := range mod.Indexes {
+		modelIndexes[idx.Name] = idx
+	}
+	liveIndexes := make(map[string]Index, len(table.Indexes))
+	for _, idx := range table.Indexes {
+		liveIndexes[idx.Name] = idx
+		modelIdx, ok := modelIndexes[idx.Name]
+		if !ok {
+			_, liveFK := liveFKs[idx.Name]
+			_, modelFK := modelFKs[idx.Name]
+			if liveFK || modelFK || table.MultiColumnFKs[idx.Name] {
+				// index is created by mysql for the foreign key.
+				continue
+			}
+		}
+		if !ok || !idx.equal(modelIdx) {
+			diff.add(alter+"DROP INDEX `"+idx.Name+"`", alter+"ADD "+idx.Definition())
+		}
+	}
+
+	modelColumns := make(map[string]bool, len(mod.Fields))
+	for _, f := range mod.Fields {
+		modelColumns[f.MWName] = true
+	}
+	liveColumns := make(map[string]liveColumn, len(table.Columns))
+	position := "FIRST"
+	for _, col := range table.Columns {
+		liveColumns[col.Name] = col
+		if !modelColumns[col.Name] {
+			// dropped columns are restored in reverse order, so they are positioned after kept columns.
+			diff.add(alter+"DROP COLUMN `"+col.Name+"`", alter+"ADD COLUMN `"+col.Name+"` "+col.definition()+" "+position)
+			continue
+		}
+		position = "AFTER `" + col.Name + "`"
+	}
+
+	position = "FIRST"
+	for _, f := range mod.Fields {
+		col, ok := liveColumns[f.MWName]
+		switch {
+		case !ok:
+			diff.add(
+				alter+"ADD COLUMN "+f.MWNameQuoted()+" "+mod.columnDefinition(f)+" "+position,
+				alter+"DROP COLUMN "+f.MWNameQuoted(),
+			)
+		case !mod.columnMatches(f, col):
+			diff.add(
+				alter+"MODIFY COLUMN "+f.MWNameQuoted()+" "+mod.columnDefinition(f),
+				alter+"MODIFY COLUMN "+f.MWNameQuoted()+" "+col.definition(),
+			)
+		}
+		position = "AFTER " + f.MWNameQuoted()
+	}
+
+	for _, idx := range mod.Indexes {
+		if live, ok := liveIndexes[idx.Name]; !ok || !idx.equal(live) {
+			diff.add(alter+"ADD "+idx.Definition(), alter+"DROP INDEX `"+idx.Name+"`")
+		}
+	}
+	for _, fk := range mod.ForeignKeys {
+		if live, ok := liveFKs[fk.Name]; !ok || !fk.equal(live) {
+			diff.add(alter+"ADD "+fk.Definition(), alter+"DROP FOREIGN KEY `"+fk.Name+"`")
+		}
+	}
+}
+
+// columnDefinition returns column definition of the field used in alter table statement,
+// primary key is declared by create table statement, so it's omitted.
+func (mod *model) columnDefinition(f *field) string {
+	if !mod.isPK(f) {
+		return f.MWType
+	}
+	return f.column.render(true, mod.pkExtra(f))
+}
+
+// pkExtra returns AUTO_INCREMENT for single integer primary key.
+func (mod *model) pkExtra(f *field) string {
+	if mod.IsCompositePK() || f.ReflectKind == reflect.String {
+		return ""
+	}
+	return "AUTO_INCREMENT"
+}
+
+// columnMatches checks whether live column matches column definition of the field.
+func (mod *model) columnMatches(f *field, col liveColumn) bool {
+	def, notNull, extra := f.column, !f.Nullable && f.column.Type != mw_json, ""
+	switch {
+	case mod.isPK(f):
+		notNull, extra = true, mod.pkExtra(f)
+	case f.MWType == mw_created:
+		def, notNull = columnDef{Type: "timestamp", Default: "CURRENT_TIMESTAMP"}, true
+	case f.MWType == mw_updated:
+		def, notNull = columnDef{Type: "timestamp", Default: "CURRENT_TIMESTAMP"}, true
+		extra = "on update CURRENT_TIMESTAMP"
+	case f.MWType == mw_deleted:
+		def, notNull = columnDef{Type: "timestamp"}, false
+	case f == mod.fieldByPos(mod.VersionPos):
+		notNull = true
+	}
+
+	colType := def.Type
+	if def.Unsigned {
+		colType += " unsigned"
+	}
+	if normalizeColumnType(colType) != normalizeColumnType(col.Type) || notNull == col.Nullable {
+		return false
+	}
+	if def.Charset != "" && !strings.EqualFold(def.Charset, col.Charset) {
+		return false
+	}
+	if def.Comment != col.Comment {
+		return false
+	}
+	liveExtra := strings.Replace(strings.ToLower(col.Extra), "default_generated", "", 1)
+	if normalizeExpr(extra) != normalizeExpr(liveExtra) {
+		return false
+	}
+
+	if def.Default == "" || strings.EqualFold(def.Default, "NULL") {
+		return col.Default == nil || strings.EqualFold(*col.Default, "NULL")
+	}
+	if col.Default == nil {
+		return false
+	}
+	return defaultsEqual(def.Default, *col.Default)
+}
+
+// equal checks whether indexes have the same columns, column order (ASC, DESC) is ignored.
+func (idx Index) equal(other Index) bool {
+	if idx.Unique != other.Unique || len(idx.Columns) != len(other.Columns) {
+		return false
+	}
+	for i := range idx.Columns {
+		if normalizeIndexColumn(idx.Columns[i]) != normalizeIndexColumn(other.Columns[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// equal checks whether foreign keys match, RESTRICT and NO ACTION actions are the same in InnoDB.
+func (fk foreignKey) equal(other foreignKey) bool {
+	return fk.Column == other.Column && fk.RefTable == other.RefTable && fk.RefColumn == other.RefColumn &&
+		referenceRule(fk.OnDelete) == referenceRule(other.OnDelete) &&
+		referenceRule(fk.OnUpdate) == referenceRule(other.OnUpdate)
+}
+
+func referenceRule(action string) string {
+	switch action = strings.ToUpper(action); action {
+	case "", "NO ACTION":
+		return "RESTRICT"
+	}
+	return action
+}
+
+func normalizeIndexColumn(col string) string {
+	col = strings.ToLower(strings.TrimSpace(col))
+	col = strings.TrimSuffix(strings.TrimSuffix(col, " desc"), " asc")
+	return strings.Replace(col, " ", "", -1)
+}
+
+// normalizeColumnType makes column types comparable, e.g. "INT(11) UNSIGNED" and "int unsigned".
+func normalizeColumnType(colType string) string {
+	colType = strings.ToLower(strings.TrimSpace(colType))
+	switch {
+	case colType == "bool" || colType == "boolean":
+		colType = "tinyint(1)"
+	case strings.HasPrefix(colType, "integer"):
+		colType = "int" + strings.TrimPrefix(colType, "integer")
+	}
+	if !strings.HasPrefix(colType, "tinyint(1)") {
+		colType = regExpIntWidth.ReplaceAllString(colType, "$1")
+	}
+	return strings.Replace(colType, " ", "", -1)
+}
+
+// normalizeExpr makes expressions comparable, e.g. "current_timestamp()" and "CURRENT_TIMESTAMP".
+func normalizeExpr(expr string) string {
+	expr = strings.ToLower(strings.TrimSpace(expr))
+	return strings.Replace(strings.Replace(expr, "()", "", -1), " ", "", -1)
+}
+
+// defaultsEqual compares raw sql default of the model with default read from INFORMATION_SCHEMA,
+// which isn't quoted by mysql and is quoted by mariadb.
+func defaultsEqual(modelDefault, liveDefault string) bool {
+	if isCurrentTimestamp(modelDefault) || isCurrentTimestamp(liveDefault) {
+		return normalizeExpr(modelDefault) == normalizeExpr(liveDefault)
+	}
+	modelDefault, liveDefault = unquoteDefault(modelDefault), unquoteDefault(liveDefault)
+	if isNumber(modelDefault) && isNumber(liveDefault) {
+		a, _ := strconv.ParseFloat(modelDefault, 64)
+		b, _ := strconv.ParseFloat(liveDefault, 64)
+		return a == b
+	}
+	return modelDefault == liveDefault
+}
+
+func unquoteDefault(def string) string {
+	if len(def) >= 2 && def[0] == '\'' && def[len(def)-1] == '\'' {
+		def = strings.Replace(def[1:len(def)-1], "''", "'", -1)
+	}
+	return def
+}
+
+func isCurrentTimestamp(def string) bool {
+	return strings.HasPrefix(normalizeExpr(def), "current_timestamp")
+}
+
+func isNumber(val string) bool {
+	_, err := strconv.ParseFloat(val, 64)
+	return err == nil
+}
+
+func isNumericType(colType string) bool {
+	colType = strings.ToLower(colType)
+	for _, prefix := range []string{"tinyint", "smallint", "mediumint", "int", "bigint", "decimal", "float", "double", "bit"} {
+		if strings.HasPrefix(colType, prefix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,90 @@
+package mwear
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+type diffUserV1 struct {
+	ID      int64
+	Name    string `mw:"size:64"`
+	Email   string `mw:"unique"`
+	Age     int
+	Created time.Time `mw:"created"`
+}
+
+func (diffUserV1) TableName() string { return "diff_user" }
+
+type diffUserV2 struct {
+	ID      int64
+	Name    string    `mw:"size:128,index"`
+	Age     int       `mw:"unsigned"`
+	Bio     string    `mw:"type:TEXT,nullable"`
+	Created time.Time `mw:"created"`
+}
+
+func (diffUserV2) TableName() string { return "diff_user" }
+
+func TestDiffSchema(t *testing.T) {
+	diff, err := DiffSchema(db, &diffUserV1{})
+	if err != nil {
+		t.Fatalf("fail diff schema: %v", err)
+	}
+	if len(diff.Up) != 1 || !strings.HasPrefix(diff.Up[0], "CREATE TABLE `diff_user`") {
+		t.Fatalf("missing table should be created, got (%s)", diff.UpSQL())
+	}
+	if diff.DownSQL() != "DROP TABLE `diff_user`;\n" {
+		t.Fatalf("missing table should be dropped by down migration, got (%s)", diff.DownSQL())
+	}
+	execDiff(t, diff.Up)
+	defer db.exec(context.Background(), OpExec, "", "DROP TABLE IF EXISTS `diff_user`")
+
+	assertSchemaUpToDate(t, &diffUserV1{})
+
+	diff, err = DiffSchema(db, &diffUserV2{})
+	if err != nil {
+		t.Fatalf("fail diff schema: %v", err)
+	}
+	expected := []string{
+		"ALTER TABLE `diff_user` DROP INDEX `uniq_email`",
+		"ALTER TABLE `diff_user` DROP COLUMN `email`",
+		"ALTER TABLE `diff_user` MODIFY COLUMN `name` VARCHAR(128) NOT NULL DEFAULT ''",
+		"ALTER TABLE `diff_user` MODIFY COLUMN `age` INT UNSIGNED NOT NULL DEFAULT 0",
+		"ALTER TABLE `diff_user` ADD COLUMN `bio` TEXT AFTER `age`",
+		"ALTER TABLE `diff_user` ADD KEY `idx_name` (`name`)",
+	}
+	if strings.Join(diff.Up, "\n") != strings.Join(expected, "\n") {
+		t.Fatalf("unexpected up statements:\n%s\nexpected:\n%s", strings.Join(diff.Up, "\n"), strings.Join(expected, "\n"))
+	}
+	if len(diff.Down) != len(diff.Up) {
+		t.Fatalf("each change should be reverted, got (%d) up and (%d) down statements", len(diff.Up), len(diff.Down))
+	}
+
+	execDiff(t, diff.Up)
+	assertSchemaUpToDate(t, &diffUserV2{})
+
+	execDiff(t, diff.Down)
+	assertSchemaUpToDate(t, &diffUserV1{})
+}
+
+func execDiff(t *testing.T, stmts []string) {
+	t.Helper()
+	for _, stmt := range stmts {
+		if _, err := db.exec(context.Background(), OpExec, "", stmt); err != nil {
+			t.Fatalf("fail exec (%s): %v", stmt, err)
+		}
+	}
+}
+
+func assertSchemaUpToDate(t *testing.T, structPtr interface{}) {
+	t.Helper()
+	diff, err := DiffSchema(db, structPtr)
+	if err != nil {
+		t.Fatalf("fail diff schema: %v", err)
+	}
+	if !diff.Empty() {
+		t.Fatalf("schema of (%T) expected to be up to date, got:\n%s", structPtr, diff.UpSQL())
+	}
+}
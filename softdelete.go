@@ -0,0 +1,150 @@
+package mwear
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/cliqueinc/mysql-wear/sqlq"
+)
+
+// softDeleteColumn returns quoted soft delete column with table name, empty if model has no soft delete field.
+func (mod *model) softDeleteColumn() string {
+	if mod.SoftDeletePos == -1 {
+		return ""
+	}
+	return "`" + mod.TableName + "`." + mod.fieldByPos(mod.SoftDeletePos).MWNameQuoted()
+}
+
+// withSoftDelete adds soft delete filter to query options of models with soft delete field.
+func (mod *model) withSoftDelete(opts []sqlq.Option) []sqlq.Option {
+	if mod.SoftDeletePos == -1 {
+		return opts
+	}
+	res := make([]sqlq.Option, 0, len(opts)+1)
+	return append(append(res, opts...), sqlq.SoftDelete(mod.softDeleteColumn()))
+}
+
+// softDelete sets deletion time of the row by primary key, rows which are already deleted keep their deletion time.
+// Deletion time of the struct is set only if the row is deleted.
+func (a *Adapter) softDelete(ctx context.Context, mod *model, rowModel reflect.Value) error {
+	now := a.currentTime()
+	column := mod.softDeleteColumn()
+	deleteSQL, err := renderTemplate(mod, "UPDATE `{{.TableName}}` SET "+column+" = ? WHERE "+mod.pkCondition(false)+" AND "+column+" IS NULL")
+	if err != nil {
+		return err
+	}
+	args := append([]interface{}{now}, mod.pkVals(rowModel)...)
+	res, err := a.exec(ctx, OpDelete, mod.TableName, deleteSQL, args...)
+	if err != nil {
+		return err
+	}
+	num, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("fail get number of affected rows: %w", err)
+	}
+	// the row is missing or already deleted, so the struct keeps its deletion time.
+	if num == 1 {
+		mod.Fields[mod.SoftDeletePos].value(rowModel, true).Set(reflect.ValueOf(now))
+	}
+
+	return nil
+}
+
+// softDeleteRows sets deletion time of not deleted rows by query, returns number of affected rows.
+func (a *Adapter) softDeleteRows(ctx context.Context, mod *model, opts []sqlq.Option) (int64, error) {
+	// soft delete filter always adds condition, so options are checked without it.
+	stmt, err := sqlq.Build(opts, sqlq.OpDelete)
+	if err != nil {
+		return 0, err
+	}
+	if !stmt.IsQueryAll && !strings.Contains(stmt.Query, "WHERE") {
+		return 0, errors.New("query options cannot be empty")
+	}
+	stmt, err = sqlq.Build(mod.withSoftDelete(opts), sqlq.OpDelete, a.currentTime())
+	if err != nil {
+		return 0, err
+	}
+	deleteSQL, err := renderTemplate(mod, "UPDATE `{{.TableName}}` SET "+mod.softDeleteColumn()+" = ? "+stmt.Query+";")
+	if err != nil {
+		return 0, err
+	}
+	res, err := a.exec(ctx, OpDelete, mod.TableName, deleteSQL, stmt.Args...)
+	if err != nil {
+		return 0, err
+	}
+	num, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("fail get number of affected rows: %w", err)
+	}
+
+	return num, nil
+}
+
+// MustRestore ensures soft deleted struct is restored without errors, panics othervise.
+func (a *Adapter) MustRestore(structPtr interface{}) {
+	if err := a.Restore(structPtr); err != nil {
+		panic(err)
+	}
+}
+
+// Restore restores soft deleted struct by primary key, setting its deletion time to NULL.
+func (a *Adapter) Restore(structPtr interface{}) error {
+	return a.RestoreContext(context.Background(), structPtr)
+}
+
+// RestoreContext is the same as Restore, but allows to cancel query using context.
+func (a *Adapter) RestoreContext(ctx context.Context, structPtr interface{}) error {
+	mod, err := parseModel(structPtr, true)
+	if err != nil {
+		return err
+	}
+	if mod.SoftDeletePos == -1 {
+		return fmt.Errorf("table (%s) has no soft delete column", mod.TableName)
+	}
+	rowModel := reflect.ValueOf(structPtr)
+	if mod.getPK(rowModel) == "" {
+		return fmt.Errorf("mw cant restore row of table (%s), ID/PK not set", mod.TableName)
+	}
+	restoreSQL, err := renderTemplate(mod, "UPDATE `{{.TableName}}` SET "+mod.softDeleteColumn()+" = NULL WHERE "+mod.pkCondition(false))
+	if err != nil {
+		return err
+	}
+	if _, err := a.exec(ctx, OpUpdate, mod.TableName, restoreSQL, mod.pkVals(rowModel)...); err != nil {
+		return err
+	}
+	field := mod.Fields[mod.SoftDeletePos].value(rowModel, true)
+	field.Set(reflect.Zero(field.Type()))
+
+	return nil
+}
+
+// MustHardDelete ensures struct is deleted without errors, panics othervise.
+func (a *Adapter) MustHardDelete(structPtr interface{}) {
+	if err := a.HardDelete(structPtr); err != nil {
+		panic(err)
+	}
+}
+
+// HardDelete deletes struct by primary key, even if model has soft delete field.
+func (a *Adapter) HardDelete(structPtr interface{}) error {
+	return a.HardDeleteContext(context.Background(), structPtr)
+}
+
+// HardDeleteContext is the same as HardDelete, but allows to cancel query using context.
+func (a *Adapter) HardDeleteContext(ctx context.Context, structPtr interface{}) error {
+	return a.delete(ctx, structPtr, true)
+}
+
+// HardDeleteRows deletes rows by specified options, even if model has soft delete field.
+// Returns number of affected rows.
+func (a *Adapter) HardDeleteRows(structPtr interface{}, opts ...sqlq.Option) (int64, error) {
+	return a.HardDeleteRowsContext(context.Background(), structPtr, opts...)
+}
+
+// HardDeleteRowsContext is the same as HardDeleteRows, but allows to cancel query using context.
+func (a *Adapter) HardDeleteRowsContext(ctx context.Context, structPtr interface{}, opts ...sqlq.Option) (int64, error) {
+	return a.deleteRows(ctx, structPtr, opts, true)
+}
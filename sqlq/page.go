@@ -0,0 +1,294 @@
+package sqlq
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// cursorTimeFormat is the format time values are kept in cursor, so mysql can compare them with datetime columns.
+const cursorTimeFormat = "2006-01-02 15:04:05.999999"
+
+// Page describes keyset pagination result.
+// Cursors are opaque strings, that should be passed to After and Before options to get next or previous page.
+type Page struct {
+	// Next is the cursor of the next page, empty if there are no more rows.
+	Next string
+	// Prev is the cursor of the previous page, empty if it's the first page.
+	Prev string
+}
+
+// Keyset describes keyset pagination of built query.
+type Keyset struct {
+	// Columns are the names of order columns, which values form page cursor.
+	Columns []string
+	// Cursor is the cursor page is fetched from, empty for the first page.
+	Cursor string
+	// Before is true in case previous page is fetched, so rows are selected in reversed order.
+	Before bool
+	// Limit is the page size, query fetches one more row to find out whether there are more rows.
+	Limit int
+}
+
+type orderColumn struct {
+	field     string
+	direction string
+}
+
+type keyset struct {
+	cursor       string
+	values       []interface{}
+	before       bool
+	uniqueFields []string
+}
+
+// After selects rows following the row the cursor points to, using order of the query.
+// Unlike offset, keyset pagination doesn't degrade on deep pages, see Adapter.SelectPage.
+// Empty cursor means the first page.
+func After(cursor string) Option {
+	return cursorOption(cursor, false)
+}
+
+// Before selects rows preceding the row the cursor points to, using order of the query.
+func Before(cursor string) Option {
+	return cursorOption(cursor, true)
+}
+
+func cursorOption(cursor string, before bool) Option {
+	return func(q *Query) (string, int, error) {
+		if q.queryType != OpSelect {
+			return "", 0, fmt.Errorf("cannot use cursor in (%s)", q.queryType)
+		}
+		var values []interface{}
+		if cursor != "" {
+			var err error
+			values, err = DecodeCursor(cursor)
+			if err != nil {
+				return "", 0, err
+			}
+		}
+
+		if q.keyset == nil {
+			q.keyset = &keyset{}
+		}
+		q.keyset.cursor = cursor
+		q.keyset.values = values
+		q.keyset.before = before
+		return "", typePagination, nil
+	}
+}
+
+// UniqueOrder enables keyset pagination and makes query order stable by adding unique fields (usually primary key)
+// to the end of the order, if the query isn't ordered by them yet. Several fields are passed in case
+// of composite unique key.
+func UniqueOrder(fields ...string) Option {
+	return func(q *Query) (string, int, error) {
+		if q.queryType != OpSelect {
+			return "", 0, fmt.Errorf("cannot use order in (%s)", q.queryType)
+		}
+		if len(fields) == 0 {
+			return "", 0, errors.New("fields cannot be empty")
+		}
+		uniqueFields := make([]string, 0, len(fields))
+		for _, field := range fields {
+			if field == "" {
+				return "", 0, errors.New("field cannot be empty")
+			}
+			if !strings.HasPrefix(field, "`") {
+				field = "`" + field + "`"
+			}
+			uniqueFields = append(uniqueFields, field)
+		}
+
+		if q.keyset == nil {
+			q.keyset = &keyset{}
+		}
+		q.keyset.uniqueFields = uniqueFields
+		return "", typeOrder, nil
+	}
+}
+
+// buildKeyset builds where condition for keyset pagination, like (a,b) > (?,?).
+func (q *Query) buildKeyset() (string, error) {
+	if q.Having != "" {
+		return "", errors.New("cannot use cursor with having")
+	}
+	if q.offset != 0 {
+		return "", errors.New("cannot use cursor with offset")
+	}
+
+	ks := q.keyset
+	for _, uniqueField := range ks.uniqueFields {
+		var ordered bool
+		for _, col := range q.orderColumns {
+			if columnName(col.field) == columnName(uniqueField) {
+				ordered = true
+				break
+			}
+		}
+		if !ordered {
+			direction := ASC
+			if len(q.orderColumns) != 0 {
+				direction = q.orderColumns[len(q.orderColumns)-1].direction
+			}
+			q.orderColumns = append(q.orderColumns, orderColumn{field: uniqueField, direction: direction})
+		}
+	}
+	if len(q.orderColumns) == 0 {
+		return "", errors.New("cursor requires query order")
+	}
+
+	q.Keyset = &Keyset{
+		Columns: make([]string, 0, len(q.orderColumns)),
+		Cursor:  ks.cursor,
+		Before:  ks.before,
+	}
+	q.order = q.order[:0]
+	for i, col := range q.orderColumns {
+		q.Keyset.Columns = append(q.Keyset.Columns, columnName(col.field))
+		// previous page is selected in reversed order, starting from the cursor.
+		if ks.before {
+			if col.direction == ASC {
+				q.orderColumns[i].direction = DESC
+			} else {
+				q.orderColumns[i].direction = ASC
+			}
+		}
+		q.order = append(q.order, fmt.Sprintf("%s %s", col.field, q.orderColumns[i].direction))
+	}
+
+	if ks.values == nil {
+		return "", nil
+	}
+	if len(ks.values) != len(q.orderColumns) {
+		return "", errors.New("cursor doesn't match query order")
+	}
+
+	sameDirection := true
+	for _, col := range q.orderColumns {
+		if col.direction != q.orderColumns[0].direction {
+			sameDirection = false
+			break
+		}
+	}
+	cmp := func(col orderColumn) string {
+		if col.direction == ASC {
+			return gt
+		}
+		return lt
+	}
+
+	if sameDirection {
+		fields := make([]string, 0, len(q.orderColumns))
+		placeholders := make([]string, 0, len(q.orderColumns))
+		for _, col := range q.orderColumns {
+			fields = append(fields, col.field)
+			placeholders = append(placeholders, "?")
+		}
+		q.Args = append(q.Args, ks.values...)
+		return fmt.Sprintf("(%s) %s (%s)", strings.Join(fields, ","), cmp(q.orderColumns[0]), strings.Join(placeholders, ",")), nil
+	}
+
+	// in case of mixed order directions expand the condition, like a > ? OR (a = ? AND b < ?).
+	conditions := make([]string, 0, len(q.orderColumns))
+	for i, col := range q.orderColumns {
+		parts := make([]string, 0, i+1)
+		for j := 0; j < i; j++ {
+			parts = append(parts, fmt.Sprintf("%s = ?", q.orderColumns[j].field))
+			q.Args = append(q.Args, ks.values[j])
+		}
+		parts = append(parts, fmt.Sprintf("%s %s ?", col.field, cmp(col)))
+		q.Args = append(q.Args, ks.values[i])
+		conditions = append(conditions, "("+strings.Join(parts, " AND ")+")")
+	}
+
+	return "(" + strings.Join(conditions, " OR ") + ")", nil
+}
+
+// columnName returns column name without quotes and table prefix.
+func columnName(field string) string {
+	field = strings.Replace(field, "`", "", -1)
+	if ind := strings.LastIndex(field, "."); ind != -1 {
+		field = field[ind+1:]
+	}
+	return field
+}
+
+// EncodeCursor encodes column values to opaque cursor.
+// Pointers are dereferenced, NULL values are not supported, since rows cannot be compared with NULL,
+// so nullable columns cannot be used in keyset pagination order.
+func EncodeCursor(values ...interface{}) (string, error) {
+	vals := make([]interface{}, 0, len(values))
+	for i, v := range values {
+		val, err := cursorValue(v)
+		if err != nil {
+			return "", fmt.Errorf("fail encode cursor: column (%d): %v", i, err)
+		}
+		vals = append(vals, val)
+	}
+	data, err := json.Marshal(vals)
+	if err != nil {
+		return "", fmt.Errorf("fail encode cursor: %v", err)
+	}
+
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// cursorValue returns the value kept in cursor, dereferencing pointers and sql null types.
+func cursorValue(v interface{}) (interface{}, error) {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return nil, errors.New("value is NULL")
+		}
+		rv = rv.Elem()
+	}
+	if !rv.IsValid() {
+		return nil, errors.New("value is NULL")
+	}
+	v = rv.Interface()
+
+	if valuer, ok := v.(driver.Valuer); ok {
+		val, err := valuer.Value()
+		if err != nil {
+			return nil, err
+		}
+		if val == nil {
+			return nil, errors.New("value is NULL")
+		}
+		v = val
+	}
+	if t, ok := v.(time.Time); ok {
+		v = t.UTC().Format(cursorTimeFormat)
+	}
+	return v, nil
+}
+
+// DecodeCursor decodes column values from cursor.
+func DecodeCursor(cursor string) ([]interface{}, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cursor: %v", err)
+	}
+
+	var values []interface{}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&values); err != nil {
+		return nil, fmt.Errorf("invalid cursor: %v", err)
+	}
+	for i, v := range values {
+		// json numbers are passed as strings, so mysql converts them to column type without loss of precision.
+		if n, ok := v.(json.Number); ok {
+			values[i] = n.String()
+		}
+	}
+
+	return values, nil
+}
@@ -0,0 +1,128 @@
+package sqlq
+
+import (
+	"database/sql"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestKeyset(t *testing.T) {
+	cursor, err := EncodeCursor("bob", 7)
+	if err != nil {
+		t.Fatalf("fail encode cursor: %v", err)
+	}
+
+	cases := []struct {
+		name  string
+		opts  []Option
+		query string
+		args  []interface{}
+	}{
+		{
+			name:  "first page",
+			opts:  []Option{Order("name", ASC), UniqueOrder("id"), After(""), Limit(10)},
+			query: " ORDER BY `name` ASC, `id` ASC LIMIT 11",
+		},
+		{
+			name:  "asc after",
+			opts:  []Option{Order("name", ASC), UniqueOrder("id"), After(cursor), Limit(10)},
+			query: "WHERE (`name`,`id`) > (?,?) ORDER BY `name` ASC, `id` ASC LIMIT 11",
+			args:  []interface{}{"bob", "7"},
+		},
+		{
+			name:  "asc before",
+			opts:  []Option{Order("name", ASC), UniqueOrder("id"), Before(cursor), Limit(10)},
+			query: "WHERE (`name`,`id`) < (?,?) ORDER BY `name` DESC, `id` DESC LIMIT 11",
+			args:  []interface{}{"bob", "7"},
+		},
+		{
+			name:  "desc after",
+			opts:  []Option{Order("name", DESC), UniqueOrder("id"), After(cursor), Limit(10)},
+			query: "WHERE (`name`,`id`) < (?,?) ORDER BY `name` DESC, `id` DESC LIMIT 11",
+			args:  []interface{}{"bob", "7"},
+		},
+		{
+			name:  "desc before",
+			opts:  []Option{Order("name", DESC), UniqueOrder("id"), Before(cursor), Limit(10)},
+			query: "WHERE (`name`,`id`) > (?,?) ORDER BY `name` ASC, `id` ASC LIMIT 11",
+			args:  []interface{}{"bob", "7"},
+		},
+		{
+			name:  "mixed after",
+			opts:  []Option{Order("name", ASC), Order("id", DESC), UniqueOrder("id"), After(cursor), Limit(10)},
+			query: "WHERE ((`name` > ?) OR (`name` = ? AND `id` < ?)) ORDER BY `name` ASC, `id` DESC LIMIT 11",
+			args:  []interface{}{"bob", "bob", "7"},
+		},
+		{
+			name:  "mixed before",
+			opts:  []Option{Order("name", ASC), Order("id", DESC), UniqueOrder("id"), Before(cursor), Limit(10)},
+			query: "WHERE ((`name` < ?) OR (`name` = ? AND `id` > ?)) ORDER BY `name` DESC, `id` ASC LIMIT 11",
+			args:  []interface{}{"bob", "bob", "7"},
+		},
+		{
+			name:  "with condition",
+			opts:  []Option{Equal("active", true), Order("id", ASC), After(""), UniqueOrder("id"), Limit(10)},
+			query: "WHERE `active` = ? ORDER BY `id` ASC LIMIT 11",
+			args:  []interface{}{true},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			q, err := Build(c.opts, OpSelect)
+			if err != nil {
+				t.Fatalf("fail build query: %v", err)
+			}
+			if q.Query != c.query {
+				t.Errorf("query:\n%s\nexpected:\n%s", q.Query, c.query)
+			}
+			if !reflect.DeepEqual(q.Args, c.args) {
+				t.Errorf("args %#v, expected %#v", q.Args, c.args)
+			}
+			if q.Keyset == nil || q.Keyset.Limit != 10 {
+				t.Errorf("unexpected keyset %+v", q.Keyset)
+			}
+		})
+	}
+}
+
+func TestKeysetErrors(t *testing.T) {
+	cursor, err := EncodeCursor(7)
+	if err != nil {
+		t.Fatalf("fail encode cursor: %v", err)
+	}
+	for name, opts := range map[string][]Option{
+		"no order":        {After(cursor)},
+		"offset":          {Order("id", ASC), After(cursor), Offset(10)},
+		"cursor mismatch": {Order("name", ASC), UniqueOrder("id"), After(cursor)},
+		"invalid cursor":  {Order("id", ASC), After("!")},
+	} {
+		if _, err := Build(opts, OpSelect); err == nil {
+			t.Errorf("%s: error expected", name)
+		}
+	}
+}
+
+func TestEncodeCursor(t *testing.T) {
+	created := time.Date(2020, 1, 2, 3, 4, 5, 6000, time.UTC)
+	id := int64(7)
+	cursor, err := EncodeCursor(&created, &id, sql.NullString{String: "bob", Valid: true})
+	if err != nil {
+		t.Fatalf("fail encode cursor: %v", err)
+	}
+	values, err := DecodeCursor(cursor)
+	if err != nil {
+		t.Fatalf("fail decode cursor: %v", err)
+	}
+	expected := []interface{}{"2020-01-02 03:04:05.000006", "7", "bob"}
+	if !reflect.DeepEqual(values, expected) {
+		t.Errorf("values %#v, expected %#v", values, expected)
+	}
+
+	var nilTime *time.Time
+	for _, v := range []interface{}{nil, nilTime, sql.NullInt64{}} {
+		if _, err := EncodeCursor(1, v); err == nil {
+			t.Errorf("error expected for NULL value %#v", v)
+		}
+	}
+}
@@ -44,6 +44,14 @@ const (
 	// typeQueryAll enforses quering all data (like where 1=1),
 	// used to prevent unintentional update or delete of all rows in table
 	typeQueryAll
+	typeSoftDelete
+)
+
+// scopes of soft deleted rows.
+const (
+	scopeNotDeleted = iota
+	scopeWithDeleted
+	scopeOnlyDeleted
 )
 
 // DefaultSelectLimit sets the default limit for select if no limit specified.
@@ -53,8 +61,13 @@ const DefaultSelectLimit = 1000
 type Query struct {
 	limit, offset int
 	order         []string
+	orderColumns  []orderColumn
 	group         []string
 	queryType     string
+	keyset        *keyset
+
+	softDeleteColumn string
+	deletedScope     int
 
 	Args       []interface{}
 	Columns    []string
@@ -62,6 +75,8 @@ type Query struct {
 	Having     string
 	IsQueryAll bool
 	Joins      []JoinConfig
+	// Keyset is set in case of keyset pagination (After, Before options).
+	Keyset *Keyset
 }
 
 // JoinConfig describes join config.
@@ -153,6 +168,34 @@ func All() Option {
 	}
 }
 
+// WithDeleted includes soft deleted rows into the result of select, get and count.
+func WithDeleted() Option {
+	return func(q *Query) (string, int, error) {
+		q.deletedScope = scopeWithDeleted
+		return "", typeSoftDelete, nil
+	}
+}
+
+// OnlyDeleted selects only soft deleted rows.
+func OnlyDeleted() Option {
+	return func(q *Query) (string, int, error) {
+		q.deletedScope = scopeOnlyDeleted
+		return "", typeSoftDelete, nil
+	}
+}
+
+// SoftDelete filters out rows having not null column, unless WithDeleted or OnlyDeleted option passed.
+// It is added by adapter for models with soft delete field, so usually there is no need to use it directly.
+func SoftDelete(column string) Option {
+	return func(q *Query) (string, int, error) {
+		if column == "" {
+			return "", 0, errors.New("soft delete column cannot be empty")
+		}
+		q.softDeleteColumn = column
+		return "", typeSoftDelete, nil
+	}
+}
+
 // IN adds IN construction to query.
 func IN(field string, values ...string) Option {
 	return func(q *Query) (string, int, error) {
@@ -295,6 +338,7 @@ func Order(field string, orderBy string) Option {
 		}
 
 		q.order = append(q.order, fmt.Sprintf("%s %s", field, orderBy))
+		q.orderColumns = append(q.orderColumns, orderColumn{field: field, direction: strings.ToUpper(orderBy)})
 		return "", typeOrder, nil
 	}
 }
@@ -370,10 +414,27 @@ func Build(opts []Option, queryType string, existingArgs ...interface{}) (*Query
 		whereOpts = append(whereOpts, optQuery)
 	}
 
+	if stmt.keyset != nil {
+		keysetQuery, err := stmt.buildKeyset()
+		if err != nil {
+			return nil, err
+		}
+		if keysetQuery != "" {
+			whereOpts = append(whereOpts, keysetQuery)
+		}
+	}
+
 	var query string
 	if len(whereOpts) != 0 {
 		query = "WHERE " + strings.Join(whereOpts, " AND ")
 	}
+	if softDeleteQuery := stmt.softDeleteQuery(); softDeleteQuery != "" {
+		if query == "" {
+			query = "WHERE " + softDeleteQuery
+		} else {
+			query = "WHERE (" + strings.Join(whereOpts, " AND ") + ") AND " + softDeleteQuery
+		}
+	}
 	if len(stmt.group) != 0 {
 		query += " GROUP BY " + strings.Join(stmt.group, ",") + " "
 	}
@@ -386,7 +447,11 @@ func Build(opts []Option, queryType string, existingArgs ...interface{}) (*Query
 	if stmt.limit == 0 && queryType == OpSelect && !isQueryAll {
 		stmt.limit = DefaultSelectLimit
 	}
-	if stmt.limit != 0 {
+	if stmt.Keyset != nil && stmt.limit != 0 {
+		// fetch one more row to find out whether there is the next page.
+		stmt.Keyset.Limit = stmt.limit
+		query += fmt.Sprintf(" LIMIT %d", stmt.limit+1)
+	} else if stmt.limit != 0 {
 		query += fmt.Sprintf(" LIMIT %d", stmt.limit)
 	}
 	if stmt.offset != 0 {
@@ -398,3 +463,18 @@ func Build(opts []Option, queryType string, existingArgs ...interface{}) (*Query
 
 	return stmt, nil
 }
+
+// softDeleteQuery returns condition on soft delete column according to the scope of deleted rows.
+func (q *Query) softDeleteQuery() string {
+	if q.softDeleteColumn == "" {
+		return ""
+	}
+	switch q.deletedScope {
+	case scopeWithDeleted:
+		return ""
+	case scopeOnlyDeleted:
+		return q.softDeleteColumn + " IS NOT NULL"
+	default:
+		return q.softDeleteColumn + " IS NULL"
+	}
+}
@@ -0,0 +1,375 @@
+package mwear
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"go/format"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// initialisms are upper cased in go names of generated models, e.g. user_id column is UserID field.
+var initialisms = map[string]bool{
+	"ID": true, "URL": true, "URI": true, "UUID": true, "API": true, "HTTP": true, "HTTPS": true, "IP": true,
+	"JSON": true, "XML": true, "HTML": true, "SQL": true, "UI": true, "UTC": true, "TTL": true, "DNS": true,
+}
+
+// tableField is a field of a model generated from existing table.
+type tableField struct {
+	GoName string
+	GoType string
+	Tag    string
+	column liveColumn
+	field  *field
+}
+
+// GenerateTableModel generates model struct of existing table, read from INFORMATION_SCHEMA,
+// along with the crud wrapper of GenerateModel. Tags are added so GenerateSchema of the model matches the table.
+func GenerateTableModel(db *DB, tableName, shortName string) (string, error) {
+	return GenerateTableModelContext(context.Background(), db, tableName, shortName)
+}
+
+// GenerateTableModelContext is the same as GenerateTableModel, but uses context.
+func GenerateTableModelContext(ctx context.Context, db *DB, tableName, shortName string) (string, error) {
+	table, err := db.tableInfo(ctx, tableName)
+	if err != nil {
+		return "", fmt.Errorf("fail get table (%s) info: %w", tableName, err)
+	}
+	if table == nil {
+		return "", fmt.Errorf("table (%s) not found", tableName)
+	}
+	return generateTableModel(tableName, shortName, table)
+}
+
+func generateTableModel(tableName, shortName string, table *tableInfo) (string, error) {
+	mod := &model{
+		StructName:    goName(tableName),
+		ShortName:     shortName,
+		TableName:     tableName,
+		PKPos:         -1,
+		CreatedPos:    -1,
+		UpdatedPos:    -1,
+		SoftDeletePos: -1,
+		VersionPos:    -1,
+	}
+	if mod.ShortName == "" {
+		for _, part := range strings.Split(tableName, "_") {
+			if part != "" {
+				mod.ShortName += strings.ToLower(part[:1])
+			}
+		}
+	}
+
+	var pkCount int
+	for _, col := range table.Columns {
+		if col.PK {
+			pkCount++
+		}
+	}
+	fields := make([]*tableField, 0, len(table.Columns))
+	for _, col := range table.Columns {
+		tf := &tableField{GoName: goName(col.Name), column: col}
+		opts, err := tf.setType(mod, pkCount)
+		if err != nil {
+			return "", fmt.Errorf("column (%s): %v", col.Name, err)
+		}
+		opts = append(opts, tf.indexOptions(table)...)
+
+		var tags []string
+		if parseName(tf.GoName) != col.Name {
+			tags = append(tags, "sql_name:"+strconv.Quote(col.Name))
+		}
+		if len(opts) != 0 {
+			tags = append(tags, "mw:"+strconv.Quote(strings.Join(opts, ",")))
+		}
+		if tag := strings.Join(tags, " "); strings.Contains(tag, "`") {
+			tf.Tag = strconv.Quote(tag)
+		} else if tag != "" {
+			tf.Tag = "`" + tag + "`"
+		}
+		if col.PK {
+			if mod.PKName == "" {
+				mod.PKName, mod.PKPos = col.Name, len(mod.Fields)
+			}
+			mod.PKFields = append(mod.PKFields, tf.field)
+		}
+		mod.Fields = append(mod.Fields, tf.field)
+		fields = append(fields, tf)
+	}
+
+	buf := &bytes.Buffer{}
+	fmt.Fprintf(buf, "type %s struct {\n", mod.StructName)
+	for _, tf := range fields {
+		fmt.Fprintf(buf, "\t%s %s %s\n", tf.GoName, tf.GoType, tf.Tag)
+	}
+	buf.WriteString("}\n")
+	if parseName(mod.StructName) != tableName {
+		fmt.Fprintf(buf, "\nfunc (%s *%s) TableName() string {\n\treturn %q\n}\n", mod.ShortName, mod.StructName, tableName)
+	}
+	if indexes := tableIndexes(table); len(indexes) != 0 {
+		fmt.Fprintf(buf, "\nfunc (%s *%s) Indexes() []mw.Index {\n\treturn []mw.Index{\n", mod.ShortName, mod.StructName)
+		for _, idx := range indexes {
+			fmt.Fprintf(buf, "\t\t{Name: %q, Columns: %#v", idx.Name, idx.Columns)
+			if idx.Unique {
+				buf.WriteString(", Unique: true")
+			}
+			buf.WriteString("},\n")
+		}
+		buf.WriteString("\t}\n}\n")
+	}
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return "", fmt.Errorf("fail format model: %v", err)
+	}
+
+	crud := ""
+	if mod.PKName != "" {
+		if crud, err = renderTemplate(mod, modelTemplate); err != nil {
+			return "", err
+		}
+	}
+	return "\n" + string(src) + crud, nil
+}
+
+// setType sets go type of the field by column type, returns mw tag options describing the column.
+func (tf *tableField) setType(mod *model, pkCount int) ([]string, error) {
+	col := tf.column
+	colType := strings.ToLower(col.Type)
+	dataType := colType
+	if ind := strings.IndexAny(dataType, "( "); ind != -1 {
+		dataType = dataType[:ind]
+	}
+	unsigned := strings.Contains(colType, "unsigned")
+
+	var t reflect.Type
+	switch dataType {
+	case "tinyint":
+		switch {
+		case strings.HasPrefix(colType, "tinyint(1)"):
+			t = reflect.TypeOf(false)
+		case unsigned:
+			t = reflect.TypeOf(uint8(0))
+		default:
+			t = reflect.TypeOf(int8(0))
+		}
+	case "smallint":
+		t = reflect.TypeOf(int16(0))
+		if unsigned {
+			t = reflect.TypeOf(uint16(0))
+		}
+	case "mediumint", "int", "integer":
+		t = reflect.TypeOf(0)
+		if unsigned {
+			t = reflect.TypeOf(uint32(0))
+		}
+	case "bigint", "bit":
+		t = reflect.TypeOf(int64(0))
+		if unsigned || dataType == "bit" {
+			t = reflect.TypeOf(uint64(0))
+		}
+	case "decimal", "numeric", "float", "double", "real":
+		t = reflect.TypeOf(float64(0))
+	case "date", "datetime", "timestamp":
+		t = reflect.TypeOf(time.Time{})
+	case "json":
+		t = reflect.TypeOf(json.RawMessage{})
+	default:
+		t = reflect.TypeOf("")
+	}
+	if col.PK {
+		// primary key may be int, int64, uint32, uint64 or string only.
+		switch t.Kind() {
+		case reflect.Int8, reflect.Int16, reflect.Bool:
+			t = reflect.TypeOf(0)
+		case reflect.Uint8, reflect.Uint16:
+			t = reflect.TypeOf(uint32(0))
+		case reflect.Int, reflect.Int64, reflect.Uint32, reflect.Uint64, reflect.String:
+		default:
+			t = reflect.TypeOf("")
+		}
+	}
+	tf.GoType = t.String()
+	if dataType == "json" {
+		// json.RawMessage may be reported as an alias.
+		tf.GoType = "json.RawMessage"
+	}
+	tf.field = &field{GoName: tf.GoName, MWName: col.Name, ReflectType: t, ReflectKind: t.Kind()}
+
+	var opts []string
+	if col.PK {
+		if pkCount > 1 || tf.GoName != "ID" {
+			opts = append(opts, "pk")
+		}
+		if err := tf.field.setPKColumn(nil); err != nil {
+			return nil, err
+		}
+		return append(opts, tf.columnOptions(tf.field.column, t)...), nil
+	}
+
+	if dataType == "timestamp" && !col.Nullable && col.Default != nil && isCurrentTimestamp(*col.Default) {
+		if strings.Contains(strings.ToLower(col.Extra), "on update") {
+			if mod.UpdatedPos == -1 {
+				mod.UpdatedPos = len(mod.Fields)
+				return []string{"updated"}, nil
+			}
+		} else if mod.CreatedPos == -1 {
+			mod.CreatedPos = len(mod.Fields)
+			return []string{"created"}, nil
+		}
+	}
+
+	base, err := tf.field.baseColumn()
+	if err != nil {
+		return nil, err
+	}
+	// JSON columns are declared without NOT NULL.
+	isJSON := dataType == "json"
+	if col.Nullable && !isJSON {
+		opts = append(opts, "nullable")
+	}
+	return append(opts, tf.columnOptions(base, t)...), nil
+}
+
+// columnOptions returns column options needed to declare live column, base is a column declared by go type of the field.
+func (tf *tableField) columnOptions(base columnDef, t reflect.Type) []string {
+	col := tf.column
+	var opts []string
+
+	baseType := base.Type
+	if base.Unsigned {
+		baseType += " unsigned"
+	}
+	liveType := normalizeColumnType(col.Type)
+	typeDiffers := normalizeColumnType(baseType) != liveType
+	// implicit default is reset by type option, so it's declared for NOT NULL columns without default.
+	resetDefault := col.Default == nil && !col.Nullable && base.Default != ""
+
+	if typeDiffers || resetDefault {
+		var opt string
+		if !resetDefault {
+			opt = typeShortcut(base, col.Type, t)
+		}
+		if opt == "" {
+			opt = "type:" + col.Type
+			base.Default = ""
+		}
+		opts = append(opts, opt)
+	}
+
+	switch {
+	case col.PK:
+	case col.Default != nil:
+		if base.Default == "" || !defaultsEqual(base.Default, *col.Default) {
+			opts = append(opts, "default:"+col.defaultSQL())
+		}
+	case base.Default != "" && col.Nullable:
+		opts = append(opts, "default:NULL")
+	}
+	if col.Comment != "" {
+		opts = append(opts, "comment:'"+col.Comment+"'")
+	}
+	return opts
+}
+
+// typeShortcut returns size, decimal or unsigned option declaring column type, empty if type option is needed.
+func typeShortcut(base columnDef, colType string, t reflect.Type) string {
+	lower := strings.ToLower(colType)
+	switch t.Kind() {
+	case reflect.String:
+		if strings.HasPrefix(lower, "varchar(") {
+			return "size:" + strings.TrimSuffix(strings.TrimPrefix(lower, "varchar("), ")")
+		}
+	case reflect.Float64:
+		if strings.HasPrefix(lower, "decimal(") {
+			return "decimal:" + strings.TrimSuffix(strings.TrimPrefix(lower, "decimal("), ")")
+		}
+	default:
+		if strings.Contains(lower, "unsigned") && !base.Unsigned &&
+			normalizeColumnType(base.Type+" unsigned") == normalizeColumnType(lower) {
+			return "unsigned"
+		}
+	}
+	return ""
+}
+
+// indexOptions returns index, unique and fk options of the field, declared by single column indexes.
+func (tf *tableField) indexOptions(table *tableInfo) []string {
+	var opts []string
+	name := tf.column.Name
+	for _, idx := range table.Indexes {
+		if len(idx.Columns) != 1 || idx.Columns[0] != name || isForeignKeyIndex(table, idx) {
+			continue
+		}
+		opt, defaultName := "index", "idx_"+name
+		if idx.Unique {
+			opt, defaultName = "unique", "uniq_"+name
+		}
+		if idx.Name != defaultName {
+			opt += ":" + idx.Name
+		}
+		opts = append(opts, opt)
+	}
+	for _, fk := range table.ForeignKeys {
+		if fk.Column != name {
+			continue
+		}
+		opts = append(opts, "fk:"+fk.RefTable+"."+fk.RefColumn)
+		if rule := referenceRule(fk.OnDelete); rule != "RESTRICT" {
+			opts = append(opts, "on_delete:"+strings.ToLower(strings.Replace(rule, " ", "_", -1)))
+		}
+		if rule := referenceRule(fk.OnUpdate); rule != "RESTRICT" {
+			opts = append(opts, "on_update:"+strings.ToLower(strings.Replace(rule, " ", "_", -1)))
+		}
+	}
+	return opts
+}
+
+// tableIndexes returns indexes, which cannot be declared by field tags: multi column and prefix indexes.
+func tableIndexes(table *tableInfo) []Index {
+	var indexes []Index
+	for _, idx := range table.Indexes {
+		if len(idx.Columns) == 1 && !strings.Contains(idx.Columns[0], "(") || isForeignKeyIndex(table, idx) {
+			continue
+		}
+		indexes = append(indexes, idx)
+	}
+	return indexes
+}
+
+// isForeignKeyIndex checks whether index is created by mysql for a foreign key.
+func isForeignKeyIndex(table *tableInfo, idx Index) bool {
+	if table.MultiColumnFKs[idx.Name] {
+		return true
+	}
+	for _, fk := range table.ForeignKeys {
+		if fk.Name == idx.Name {
+			return true
+		}
+	}
+	return false
+}
+
+// goName converts sql name into go name, e.g. user_id is UserID.
+func goName(sqlName string) string {
+	parts := strings.FieldsFunc(sqlName, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	var name string
+	for _, part := range parts {
+		if upper := strings.ToUpper(part); initialisms[upper] {
+			name += upper
+			continue
+		}
+		runes := []rune(part)
+		name += string(unicode.ToUpper(runes[0])) + string(runes[1:])
+	}
+	if name == "" || unicode.IsDigit([]rune(name)[0]) {
+		name = "Col" + name
+	}
+	return name
+}
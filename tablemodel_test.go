@@ -0,0 +1,93 @@
+package mwear
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestGenerateTableModel(t *testing.T) {
+	ctx := context.Background()
+	_, err := db.exec(ctx, OpExec, "", "CREATE TABLE `legacy_user_email` ("+
+		"`id` BIGINT NOT NULL AUTO_INCREMENT PRIMARY KEY,"+
+		"`userId` INT NOT NULL DEFAULT 0,"+
+		"`email` VARCHAR(128) NOT NULL DEFAULT '',"+
+		"`note` TEXT,"+
+		"`price` DECIMAL(12,2) NOT NULL DEFAULT 0 COMMENT 'in usd',"+
+		"`created` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,"+
+		"UNIQUE KEY `uniq_email` (`email`),"+
+		"KEY `idx_user_created` (`userId`, `created`))")
+	if err != nil {
+		t.Fatalf("fail create table: %v", err)
+	}
+	defer db.exec(ctx, OpExec, "", "DROP TABLE `legacy_user_email`")
+
+	model, err := GenerateTableModel(db, "legacy_user_email", "")
+	if err != nil {
+		t.Fatalf("fail generate model: %v", err)
+	}
+	for _, expected := range []string{
+		"type LegacyUserEmail struct {",
+		"ID      int64\n",
+		"UserId  int       `sql_name:\"userId\"`",
+		"Email   string    `mw:\"size:128,unique\"`",
+		"Note    string    `mw:\"nullable,type:text\"`",
+		"Price   float64   `mw:\"decimal:12,2,comment:'in usd'\"`",
+		"Created time.Time `mw:\"created\"`",
+		`{Name: "idx_user_created", Columns: []string{"userId", "created"}}`,
+		"func GetLegacyUserEmail(db *mw.DB, id int64) (*LegacyUserEmail, error) {",
+		"lue := &LegacyUserEmail{ID: id}",
+	} {
+		if !strings.Contains(model, expected) {
+			t.Errorf("model doesn't contain (%s):\n%s", expected, model)
+		}
+	}
+
+	if _, err := GenerateTableModel(db, "legacy_missing", ""); err == nil {
+		t.Fatalf("error expected for missing table")
+	}
+}
+
+func TestGenerateTableModelCompositePK(t *testing.T) {
+	ctx := context.Background()
+	_, err := db.exec(ctx, OpExec, "", "CREATE TABLE `legacy_user_group` ("+
+		"`user_id` BIGINT NOT NULL,"+
+		"`group_id` INT NOT NULL,"+
+		"`note` VARCHAR(64) NOT NULL DEFAULT '' COMMENT 'say \"hi\"',"+
+		"PRIMARY KEY (`user_id`, `group_id`))")
+	if err != nil {
+		t.Fatalf("fail create table: %v", err)
+	}
+	defer db.exec(ctx, OpExec, "", "DROP TABLE `legacy_user_group`")
+
+	model, err := GenerateTableModel(db, "legacy_user_group", "")
+	if err != nil {
+		t.Fatalf("fail generate model: %v", err)
+	}
+	for _, expected := range []string{
+		"UserID  int64  `mw:\"pk\"`",
+		"GroupID int    `mw:\"pk\"`",
+		"Note    string `mw:\"size:64,comment:'say \\\"hi\\\"'\"`",
+		"func GetLegacyUserGroup(db *mw.DB, userID int64, groupID int) (*LegacyUserGroup, error) {",
+		"lug := &LegacyUserGroup{UserID: userID, GroupID: groupID}",
+	} {
+		if !strings.Contains(model, expected) {
+			t.Errorf("model doesn't contain (%s):\n%s", expected, model)
+		}
+	}
+}
+
+func TestGoName(t *testing.T) {
+	cases := map[string]string{
+		"user_email":  "UserEmail",
+		"user_id":     "UserID",
+		"redirectUrl": "RedirectUrl",
+		"api_key":     "APIKey",
+		"2fa":         "Col2fa",
+	}
+	for sqlName, expected := range cases {
+		if name := goName(sqlName); name != expected {
+			t.Errorf("go name of (%s): expected (%s), got (%s)", sqlName, expected, name)
+		}
+	}
+}
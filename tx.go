@@ -0,0 +1,160 @@
+package mwear
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Default retry settings of RunInTx.
+const (
+	DefaultTxMaxRetries = 3
+	DefaultTxRetryDelay = 50 * time.Millisecond
+)
+
+// TxOptions configures transaction started by RunInTx.
+type TxOptions struct {
+	// Isolation is the transaction isolation level, mysql default (repeatable read) is used if not set.
+	Isolation sql.IsolationLevel
+	ReadOnly  bool
+
+	// MaxRetries is the max number of retries in case of deadlock or lock wait timeout,
+	// DefaultTxMaxRetries is used if not set, negative value disables retries.
+	MaxRetries int
+	// RetryDelay is the delay before the first retry, doubled on each next one.
+	// DefaultTxRetryDelay is used if not set.
+	RetryDelay time.Duration
+}
+
+type txBeginner interface {
+	BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error)
+}
+
+// InTx checks whether adapter wraps a transaction.
+func (a *Adapter) InTx() bool {
+	if a.inTx {
+		return true
+	}
+	_, ok := a.connection().(*sql.Tx)
+	return ok
+}
+
+// connection returns wrapped connection.
+func (a *Adapter) connection() interface{} {
+	if a.ctxCon != nil {
+		return a.ctxCon
+	}
+	return a.con
+}
+
+// RunInTx runs fn in transaction, fn receives adapter bound to the transaction.
+// Transaction is committed in case fn succeeds, and rolled back in case fn returns an error or panics.
+// In case mysql returns deadlock (1213) or lock wait timeout (1205) error, the whole fn
+// is retried with exponential backoff, so fn should not have side effects outside of the transaction.
+// opts may be nil.
+//
+// If adapter already wraps a transaction (e.g. RunInTx is called inside another RunInTx),
+// fn is run in nested scope using savepoint: in case fn fails, only changes made
+// by fn are rolled back (ROLLBACK TO SAVEPOINT), otherwise savepoint is released.
+// opts are ignored in this case, and fn is not retried, as deadlock rolls back the whole transaction,
+// so the outermost RunInTx retries it.
+func (a *Adapter) RunInTx(ctx context.Context, opts *TxOptions, fn func(a *Adapter) error) error {
+	if a.InTx() {
+		return a.runInSavepoint(ctx, fn)
+	}
+
+	beginner, ok := a.connection().(txBeginner)
+	if !ok {
+		return errors.New("connection doesn't support transactions")
+	}
+	if opts == nil {
+		opts = &TxOptions{}
+	}
+	maxRetries := opts.MaxRetries
+	if maxRetries == 0 {
+		maxRetries = DefaultTxMaxRetries
+	}
+	delay := opts.RetryDelay
+	if delay <= 0 {
+		delay = DefaultTxRetryDelay
+	}
+	txOpts := &sql.TxOptions{Isolation: opts.Isolation, ReadOnly: opts.ReadOnly}
+
+	for attempt := 0; ; attempt++ {
+		err := a.runInTx(ctx, beginner, txOpts, fn)
+		if err == nil {
+			return nil
+		}
+		if attempt >= maxRetries || !(IsDeadlockError(err) || IsLockWaitTimeoutError(err)) {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+}
+
+func (a *Adapter) runInTx(ctx context.Context, beginner txBeginner, txOpts *sql.TxOptions, fn func(a *Adapter) error) error {
+	tx, err := beginner.BeginTx(ctx, txOpts)
+	if err != nil {
+		return fmt.Errorf("fail start transaction: %w", err)
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			tx.Rollback()
+			panic(p)
+		}
+	}()
+
+	txAdapter := *a
+	txAdapter.con = tx
+	txAdapter.ctxCon = tx
+	txAdapter.inTx = true
+	txAdapter.txDepth = 0
+	if err := fn(&txAdapter); err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			return fmt.Errorf("%w (rollback failed: %v)", err, rbErr)
+		}
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("fail commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+func (a *Adapter) runInSavepoint(ctx context.Context, fn func(a *Adapter) error) error {
+	nested := *a
+	nested.inTx = true
+	nested.txDepth++
+	savepoint := fmt.Sprintf("`mw_sp_%d`", nested.txDepth)
+
+	if _, err := a.exec(ctx, OpSavepoint, "", "SAVEPOINT "+savepoint); err != nil {
+		return fmt.Errorf("fail create savepoint: %w", err)
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			a.exec(ctx, OpSavepoint, "", "ROLLBACK TO SAVEPOINT "+savepoint)
+			panic(p)
+		}
+	}()
+
+	if err := fn(&nested); err != nil {
+		if _, rbErr := a.exec(ctx, OpSavepoint, "", "ROLLBACK TO SAVEPOINT "+savepoint); rbErr != nil {
+			return fmt.Errorf("%w (rollback to savepoint failed: %v)", err, rbErr)
+		}
+		return err
+	}
+	if _, err := a.exec(ctx, OpSavepoint, "", "RELEASE SAVEPOINT "+savepoint); err != nil {
+		return fmt.Errorf("fail release savepoint: %w", err)
+	}
+
+	return nil
+}
@@ -26,7 +26,7 @@ func GetColumnName(modPtr interface{}, colName string) string {
 	escapeString := func(str string) string {
 		return "`" + trimString(str) + "`"
 	}
-	modInfo := parseModel(modPtr, false)
+	modInfo := mustParseModel(modPtr, false)
 
 	return "`" + modInfo.TableName + "`." + escapeString(colName)
 }
@@ -49,10 +49,11 @@ func MakeOrderBy(modelPtr interface{}, sortBy string) (orderField, direction str
 		sortBy = sortBy[len("-"):]
 	}
 
-	var (
-		mod         = parseModel(modelPtr, false)
-		fieldExists bool
-	)
+	mod, err := parseModel(modelPtr, false)
+	if err != nil {
+		return "", "", false
+	}
+	var fieldExists bool
 	for _, f := range mod.Fields {
 		if f.MWName == sortBy || strings.ToLower(f.GoName) == strings.ToLower(sortBy) {
 			fieldExists = true
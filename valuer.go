@@ -0,0 +1,47 @@
+package mwear
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"reflect"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+var (
+	scannerType = reflect.TypeOf((*sql.Scanner)(nil)).Elem()
+	valuerType  = reflect.TypeOf((*driver.Valuer)(nil)).Elem()
+)
+
+// nullTypes are column types of standard nullable types, used in case column type isn't declared with type tag.
+var nullTypes = map[reflect.Type]string{
+	reflect.TypeOf(sql.NullString{}):  "VARCHAR(255)",
+	reflect.TypeOf(sql.NullInt64{}):   "BIGINT",
+	reflect.TypeOf(sql.NullInt32{}):   "INT",
+	reflect.TypeOf(sql.NullFloat64{}): "DOUBLE",
+	reflect.TypeOf(sql.NullBool{}):    "tinyint(1)",
+	reflect.TypeOf(sql.NullTime{}):    "timestamp",
+	reflect.TypeOf(mysql.NullTime{}):  "timestamp",
+}
+
+// isCustomType checks whether values of the type implement sql.Scanner or driver.Valuer,
+// so they are passed to the driver as is.
+func isCustomType(t reflect.Type) bool {
+	if t.Kind() == reflect.Ptr {
+		return false
+	}
+	ptrType := reflect.PtrTo(t)
+	return t.Implements(valuerType) || ptrType.Implements(valuerType) || ptrType.Implements(scannerType)
+}
+
+// customVal returns value of custom type field to be passed to the driver.
+func customVal(fieldVal reflect.Value) interface{} {
+	if fieldVal.Type().Implements(valuerType) || !reflect.PtrTo(fieldVal.Type()).Implements(valuerType) {
+		return fieldVal.Interface()
+	}
+	// Value has pointer receiver, the copy is passed so the value isn't changed by the caller later,
+	// which matters for dirty tracking snapshots.
+	ptr := reflect.New(fieldVal.Type())
+	ptr.Elem().Set(fieldVal)
+	return ptr.Interface()
+}
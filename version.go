@@ -1,6 +1,7 @@
 package mwear
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"io/ioutil"
@@ -244,7 +245,7 @@ func (db *DB) UpdateSchema(execDefault bool) error {
 			continue
 		}
 
-		migrationFunc := func(con Connection) error {
+		migrationFunc := func(a *Adapter) error {
 			// Split migration file content into separate queries.
 			queries := strings.Split(m.upSQL, ";")
 			// Apply query by query in a loop.
@@ -252,12 +253,12 @@ func (db *DB) UpdateSchema(execDefault bool) error {
 				if !regExpNotEmptyString.MatchString(query) {
 					continue
 				}
-				_, err := con.Exec(query)
+				_, err := a.exec(context.Background(), OpExec, "", query)
 				if err != nil {
 					return fmt.Errorf("exec query: (%s)\nerr: (%s)", query, err)
 				}
 			}
-			_, err := Wrap(con).Insert(&SchemaMigration{Version: version, Created: time.Now().UTC()})
+			_, err := a.Insert(&SchemaMigration{Version: version, Created: time.Now().UTC()})
 			if err != nil {
 				return fmt.Errorf("insert to mw_schema_migration: %s", err)
 			}
@@ -285,13 +286,13 @@ func (db *DB) UpdateSchema(execDefault bool) error {
 }
 
 // execInTx executes migration under transaction, panics on transaction errors.
-func (db *DB) execInTx(version string, migrationFunc func(con Connection) error) error {
+func (db *DB) execInTx(version string, migrationFunc func(a *Adapter) error) error {
 	tx, err := db.DB.Begin()
 	if err != nil {
 		return fmt.Errorf("fail start transaction: %v", err)
 	}
 
-	if err := migrationFunc(tx); err != nil {
+	if err := migrationFunc(db.wrap(tx)); err != nil {
 		if err := tx.Rollback(); err != nil {
 			panic(fmt.Sprintf("migration (%s): failed to rollback transaction: %v\n", version, err))
 		}
@@ -322,14 +323,13 @@ func (db *DB) ExecuteMigration(version string) error {
 		return fmt.Errorf("migration (%s): up sql not defined", version)
 	}
 
-	migrationFunc := func(con Connection) error {
-		_, err := con.Exec(m.upSQL)
+	migrationFunc := func(a *Adapter) error {
+		_, err := a.exec(context.Background(), OpExec, "", m.upSQL)
 		if err != nil {
 			return err
 		}
 		schemaMigration := &SchemaMigration{}
 
-		a := Wrap(con)
 		found, err := a.Get(schemaMigration, sqlq.Equal("version", version))
 		if err != nil {
 			return fmt.Errorf("fail get schema migration (%s): %v", version, err)
@@ -363,41 +363,34 @@ func InitMigration(isDefault bool) error {
 	if isDefault {
 		newVersion = DefaultVersion
 	}
+	return writeMigrationFiles(newVersion, `-- paste here migration sql code`, `-- paste here migration rollback sql code`)
+}
 
-	f, err := os.Create(getMigrationPath() + newVersion + ".sql")
-	if err != nil {
-		return fmt.Errorf("fail create migration sql file: %v", err)
-	}
-	sqlTmpl := `-- paste here migration sql code`
-	if _, err := f.WriteString(sqlTmpl); err != nil {
-		if err := f.Close(); err != nil {
-			return fmt.Errorf("failed to close sql file: %v", err)
-		}
+// writeMigrationFiles creates up and down migration files of the version in migration path.
+func writeMigrationFiles(version, upSQL, downSQL string) error {
+	upFile := getMigrationPath() + version + ".sql"
+	if err := writeFile(upFile, upSQL); err != nil {
 		return fmt.Errorf("fail init sql file: %v", err)
 	}
-	if err := f.Close(); err != nil {
-		return fmt.Errorf("failed to close sql file: %v", err)
+	if err := writeFile(getMigrationPath()+version+"_down.sql", downSQL); err != nil {
+		if err := os.Remove(upFile); err != nil {
+			return fmt.Errorf("failed to remove sql file: %v", err)
+		}
+		return fmt.Errorf("fail init down sql file: %v", err)
 	}
+	return nil
+}
 
-	f, err = os.Create(getMigrationPath() + newVersion + "_down.sql")
+func writeFile(name, content string) error {
+	f, err := os.Create(name)
 	if err != nil {
-		return fmt.Errorf("fail create down migration sql file: %v", err)
-	}
-	sqlTmpl = `-- paste here migration rollback sql code`
-	if _, err := f.WriteString(sqlTmpl); err != nil {
-		if err := f.Close(); err != nil {
-			return fmt.Errorf("failed to close sql file: %v", err)
-		}
-		if err := os.Remove(getMigrationPath() + newVersion + ".sql"); err != nil {
-			return fmt.Errorf("failed to remove sql file: %v", err)
-		}
-		return fmt.Errorf("fail init sql file: %v", err)
+		return err
 	}
-	if err := f.Close(); err != nil {
-		return fmt.Errorf("failed to close sql file: %v", err)
+	if _, err := f.WriteString(content); err != nil {
+		f.Close()
+		return err
 	}
-
-	return nil
+	return f.Close()
 }
 
 // RollbackLatest rollbacks latest migration.
@@ -488,7 +481,7 @@ func (db *DB) rollback(version string, m migration) error {
 		return fmt.Errorf("migration (%s): down sql not found", version)
 	}
 
-	migrationFunc := func(con Connection) error {
+	migrationFunc := func(a *Adapter) error {
 		// Split migration file content into separate queries.
 		queries := strings.Split(m.downSQL, ";")
 		// Apply query by query in a loop.
@@ -496,12 +489,12 @@ func (db *DB) rollback(version string, m migration) error {
 			if !regExpNotEmptyString.MatchString(query) {
 				continue
 			}
-			_, err := con.Exec(query)
+			_, err := a.exec(context.Background(), OpExec, "", query)
 			if err != nil {
 				return err
 			}
 		}
-		if _, err := Wrap(con).DeleteRows(&SchemaMigration{}, sqlq.Equal("version", version)); err != nil {
+		if _, err := a.DeleteRows(&SchemaMigration{}, sqlq.Equal("version", version)); err != nil {
 			return err
 		}
 
@@ -1,6 +1,7 @@
 package mwear
 
 import (
+	"errors"
 	"testing"
 	"time"
 
@@ -96,7 +97,8 @@ func TestMigrationHandler(t *testing.T) {
 		t.Fatalf("user table should have been deleted")
 	} else {
 		var tableNotExists bool
-		if mysqlError, ok := err.(*mysql.MySQLError); ok {
+		var mysqlError *mysql.MySQLError
+		if errors.As(err, &mysqlError) {
 			if mysqlError.Number == 1146 { // table not exists error code for mysql
 				tableNotExists = true
 			}